@@ -5,7 +5,12 @@ go 1.14
 require (
 	github.com/gin-contrib/cors v1.3.1
 	github.com/gin-gonic/gin v1.6.2
+	github.com/golang-jwt/jwt v3.2.2+incompatible
+	github.com/golang/protobuf v1.4.3
 	github.com/prometheus/client_golang v0.9.3
 	github.com/spf13/viper v1.6.2
 	github.com/stretchr/testify v1.5.1
+	go.etcd.io/bbolt v1.3.4
+	google.golang.org/grpc v1.29.1
+	google.golang.org/protobuf v1.25.0
 )
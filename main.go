@@ -1,7 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/theblueskies/spothro/rates"
@@ -14,22 +23,193 @@ func main() {
 	viper.SetDefault("PORT", "9000")
 	port := viper.GetString("PORT")
 	port = ":" + port
+	// GRPC_PORT is used to decide which port the gRPC server will run on
+	// The default is set to 9001
+	viper.BindEnv("GRPC_PORT")
+	viper.SetDefault("GRPC_PORT", "9001")
+	grpcPort := ":" + viper.GetString("GRPC_PORT")
 	// SEED_RATE_FILE is used to decide which seed rate file to load initial rates from
 	// The default is set to "rates/seed_rates.json"
 	viper.BindEnv("SEED_RATE_FILE")
 	viper.SetDefault("SEED_RATE_FILE", "rates/seed_rates.json")
 	seedRateFile := viper.GetString("SEED_RATE_FILE")
+	// RATE_STORE is used to decide where accepted rates are persisted. It can be "memory", "file" or "bolt"
+	// The default is set to "memory", which means the rates are reseeded on every restart
+	viper.BindEnv("RATE_STORE")
+	viper.SetDefault("RATE_STORE", "memory")
+	rateStore := viper.GetString("RATE_STORE")
+	// RATE_STORE_PATH is used to decide which file the "file" and "bolt" rate stores persist rates to
+	// The default is set to "rates.json" for the "file" store and "rates.db" for the "bolt" store
+	viper.BindEnv("RATE_STORE_PATH")
+	rateStorePath := viper.GetString("RATE_STORE_PATH")
+	// AUDIT_LOG_PATH is used to decide which file a record of every accepted set of rates is appended to
+	// The default is set to "rates_audit.jsonl"
+	viper.BindEnv("AUDIT_LOG_PATH")
+	viper.SetDefault("AUDIT_LOG_PATH", "rates_audit.jsonl")
+	auditLogPath := viper.GetString("AUDIT_LOG_PATH")
+	// VERSION_LOG_PATH is used to decide which file every version of the rates is appended to when the rates are persisted,
+	// so that the versions are kept across restarts. The default is set to "rates_versions.jsonl"
+	viper.BindEnv("VERSION_LOG_PATH")
+	viper.SetDefault("VERSION_LOG_PATH", "rates_versions.jsonl")
+	versionLogPath := viper.GetString("VERSION_LOG_PATH")
+
+	// AUTH_API_KEYS is used to decide which API keys are accepted, in the format name:role:key separated by commas
+	// The role is either "quote", which can only get quotes, or "admin", which can also update the rates
+	viper.BindEnv("AUTH_API_KEYS")
+	authAPIKeys := viper.GetString("AUTH_API_KEYS")
+	// AUTH_JWT_SECRET is used to verify JWTs that are signed with HMAC. Their sub claim names the caller
+	// and their role claim is either "quote" or "admin"
+	viper.BindEnv("AUTH_JWT_SECRET")
+	authJWTSecret := viper.GetString("AUTH_JWT_SECRET")
+	// AUTH_DISABLED is used to run the service without authentication, which opens every endpoint to anyone
+	// The default is set to false, so the service refuses to start without API keys or a JWT secret
+	viper.BindEnv("AUTH_DISABLED")
+	viper.SetDefault("AUTH_DISABLED", false)
+	authDisabled := viper.GetBool("AUTH_DISABLED")
+
+	var auth *rates.Auth
+	switch {
+	case authAPIKeys != "" || authJWTSecret != "":
+		apiKeys, err := rates.ParseAPIKeys(authAPIKeys)
+		if err != nil {
+			log.Fatalf("invalid AUTH_API_KEYS: %v", err)
+		}
+		auth = &rates.Auth{APIKeys: apiKeys, JWTSecret: []byte(authJWTSecret)}
+	case authDisabled:
+		log.Println("authentication is disabled, every endpoint is open")
+	default:
+		log.Fatalf("no credentials are configured, set AUTH_API_KEYS or AUTH_JWT_SECRET, or AUTH_DISABLED=true to run without authentication")
+	}
+
+	// The facilities and the rates of every facility are persisted next to the rates, each facility
+	// to its own file or key
+	var store rates.RateStore
+	var facilityStore rates.FacilityStore
+	var storeFor func(id string) rates.RateStore
+	// closeStore releases the store once the service has stopped, when the store has to be closed
+	var closeStore func() error
+	switch rateStore {
+	case "memory":
+	case "file":
+		if rateStorePath == "" {
+			rateStorePath = "rates.json"
+		}
+		store = rates.NewFileStore(rateStorePath)
+		facilityStore = rates.NewFileFacilityStore(siblingPath(rateStorePath, "facilities"))
+		storeFor = func(id string) rates.RateStore {
+			return rates.NewFileStore(siblingPath(rateStorePath, "facility-"+id))
+		}
+	case "bolt":
+		if rateStorePath == "" {
+			rateStorePath = "rates.db"
+		}
+		boltStore, err := rates.NewBoltStore(rateStorePath)
+		if err != nil {
+			log.Fatalf("failed to open the bolt rate store at %s: %v", rateStorePath, err)
+		}
+		closeStore = boltStore.Close
+		store = boltStore
+		facilityStore = boltStore
+		storeFor = func(id string) rates.RateStore {
+			return boltStore.Facility(id)
+		}
+	default:
+		log.Fatalf("unknown RATE_STORE: %s", rateStore)
+	}
+
+	// Every accepted set of rates is audited, whether it is put for a facility or not
+	audit := rates.NewFileAuditLog(auditLogPath)
+	// The versions are only persisted along with the rates that they record
+	var versions rates.VersionLog
+	if store != nil {
+		versions = rates.NewFileVersionLog(versionLogPath)
+	}
 
 	// Get an instance of the API
-	api, err := rates.NewAPI(seedRateFile)
+	api, err := rates.NewAPIWithOptions(seedRateFile, rates.Options{
+		Store:    store,
+		Audit:    audit,
+		Versions: versions,
+	})
 	if err != nil {
-		panic(err)
+		log.Fatalf("failed to load the rates: %v", err)
 	}
 	log.Println(port)
 
-	// Get an instance of the router and pass in the API as parameter
-	// rates.API implements the rates.Service interface
-	router := rates.NewRouter(api)
+	// The gRPC server is started on its own port and shares the API with the router
+	lis, err := net.Listen("tcp", grpcPort)
+	if err != nil {
+		log.Fatalf("failed to listen on %s: %v", grpcPort, err)
+	}
+	log.Println(grpcPort)
+	grpcServer := rates.NewGRPCServerWithAuth(api, auth)
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Fatalf("gRPC server stopped: %v", err)
+		}
+	}()
+
+	// Every facility has its own rate table, which starts out empty
+	facilityOptions := rates.FacilityOptions{
+		Rates: func(id string) rates.Options {
+			return rates.Options{Audit: audit}
+		},
+	}
+	if store != nil {
+		facilityOptions = rates.FacilityOptions{
+			Store: facilityStore,
+			Rates: func(id string) rates.Options {
+				return rates.Options{
+					Store:    storeFor(id),
+					Audit:    audit,
+					Versions: rates.NewFileVersionLog(siblingPath(versionLogPath, "facility-"+id)),
+				}
+			},
+		}
+	}
+	facilities, err := rates.NewFacilitiesWithOptions(facilityOptions)
+	if err != nil {
+		log.Fatalf("failed to load the facilities: %v", err)
+	}
+
+	// Get an instance of the router and pass in the API, the facilities and the authentication as parameters
+	// rates.API implements the rates.Service interface and rates.Facilities implements
+	// the rates.FacilityService interface
+	router := rates.NewRouterWithOptions(api, rates.RouterOptions{Facilities: facilities, Auth: auth})
+	srv := &http.Server{Addr: port, Handler: router}
+
+	// On SIGINT or SIGTERM, the servers stop taking requests and let the requests in flight finish,
+	// so that nothing writes to the store after it is closed
+	stopped := make(chan struct{})
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+		log.Println("shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("failed to shut down the server: %v", err)
+		}
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
 	// the service is started
-	router.Run(port)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatalf("server stopped: %v", err)
+	}
+	<-stopped
+	if closeStore != nil {
+		if err := closeStore(); err != nil {
+			log.Fatalf("failed to close the rate store: %v", err)
+		}
+	}
+}
+
+// siblingPath returns the path of a file next to the file at path, named after it with name added before
+// its extension, such as rates.facilities.json for rates.json
+func siblingPath(path, name string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + name + ext
 }
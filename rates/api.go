@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,29 +25,139 @@ var dayMap = map[string]string{
 	"sun":   "Sunday",
 }
 
-// DayRate is used to store the price for a given time range for a specific day
+// DayRate is used to store the price for a given wall clock time range for a specific day
+// in the timezone of the rate. The times are held as seconds since midnight, where the end
+// of the day is 86400.
 type DayRate struct {
 	day       string
-	startTime float32
-	endTime   float32
+	startTime int
+	endTime   int
 	price     int
 	tz        string
+	loc       *time.Location
+	index     int
+	// effectiveUntil is the time the rate stops being in force. It is zero when the rate doesn't expire.
+	effectiveUntil time.Time
+	// pricing charges the rate by duration instead of the flat price when it is set
+	pricing *Pricing
+	// boundary decides if the instants at the start and end of the window belong to it
+	boundary string
+	// override is the name of the override that the rate belongs to. Its index is -1 then.
+	override string
+}
+
+// charge returns the price of the rate for the time range from start to end.
+// offset is how long the car has already been parked for before start, and daily holds what the rate
+// has already charged on the same date, which counts towards its daily maximum.
+func (r DayRate) charge(start, end time.Time, offset time.Duration, daily dailyCharges) int {
+	if r.pricing == nil {
+		return r.price
+	}
+	price := r.pricing.charge(offset, end.Sub(start))
+	if r.pricing.DailyMax > 0 {
+		if remaining := r.pricing.DailyMax - daily[r.key(start)]; price > remaining {
+			price = remaining
+		}
+	}
+	return price
+}
+
+// rateTable holds a set of rates that was accepted by Put, keyed by weekday
+// A table is never modified once it is built, so it can be read without holding a lock
+type rateTable struct {
+	rateMap      map[string][]DayRate
+	locations    []*time.Location
+	allowOverlap string
+	rates        IncomingRates
+	// index holds the same rates as rateMap in an interval tree for each weekday and timezone
+	index map[indexKey]*intervalTree
+	// overrides take precedence over the weekday rates on their dates, the most specific first
+	overrides []dateOverride
+	// effectiveFrom is the time the table takes effect. It is zero when the table took effect immediately.
+	effectiveFrom time.Time
 }
 
 // ParkingTimesRequest is used to deserialize and hold the input time ranges
+// When MultiDay is set, time ranges that span midnight or several days are priced per day
+// When Stitch is set to "sum" or "max", time ranges can be priced by several contiguous windows
 type ParkingTimesRequest struct {
 	StartTime time.Time `form:"start_time" json:"start_time"`
 	EndTime   time.Time `form:"end_time" json:"end_time"`
+	MultiDay  bool      `form:"multi_day" json:"multi_day"`
+	Stitch    string    `form:"stitch" json:"stitch"`
+}
+
+// Quote holds the total rate for a time range and the per-day segments that make it up
+// Windows holds the windows that were stitched together to price the time range, if any
+type Quote struct {
+	Rate     int
+	Segments []Segment
+	Windows  []Window
+}
+
+// pricer prices a time range that lies within a single day and returns the windows it was stitched from.
+// offset is how long the car has already been parked for before start.
+type pricer func(start, end time.Time, offset time.Duration) (int, []Window, error)
+
+// Segment is the part of a time range that falls within a single day and the rate for it
+type Segment struct {
+	StartTime time.Time `json:"start_time" xml:"start_time"`
+	EndTime   time.Time `json:"end_time" xml:"end_time"`
+	Rate      int       `json:"rate" xml:"rate"`
 }
 
 // API implements the interface to get rates and store new rates
 type API struct {
-	rateMap map[string][]DayRate
-	mu      sync.Mutex
+	// tables holds the []*rateTable ordered by the time they take effect. Each table is in force until the
+	// next one takes effect. The slice and its tables are never modified once they are stored, so they are
+	// read without holding mu. Writers hold mu while they build and store a new slice.
+	tables   atomic.Value
+	versions []Version
+	store    RateStore
+	audit    AuditLog
+	// versionLog persists the versions when it is set
+	versionLog VersionLog
+	// facility is the id of the facility that the rates belong to. It is empty for the rates at /rates.
+	facility string
+	// lastID is the highest numeric id of any rate that has been put. New rates are numbered after it.
+	lastID int
+	mu     sync.Mutex
+}
+
+// Options configures the optional collaborators of an API. The zero value keeps the rates in memory only
+// and doesn't keep an audit log.
+type Options struct {
+	// Store saves every accepted set of rates. The rates are loaded from it on startup and the API is
+	// only seeded with the default JSON data file when it doesn't have any rates yet.
+	Store RateStore
+	// Audit is appended a record of every set of rates that the API accepts.
+	// Loading the stored or seed rates on startup isn't audited.
+	Audit AuditLog
+	// Versions is appended every version of the rates. The versions are restored from it on startup, so that
+	// they keep their ids across restarts. It should only be set along with a Store.
+	Versions VersionLog
 }
 
 // NewAPI returns a new instance of API. It is seeded with the default JSON data file
 func NewAPI(seedRatesFile string) (*API, error) {
+	return NewAPIWithOptions(seedRatesFile, Options{})
+}
+
+// NewAPIWithOptions returns a new instance of API with the store, audit log and version log of opts.
+// It is seeded with the default JSON data file unless the store already has rates.
+func NewAPIWithOptions(seedRatesFile string, opts Options) (*API, error) {
+	a := &API{}
+	loaded, err := a.load(opts.Store)
+	if err != nil {
+		return nil, err
+	}
+	if loaded {
+		if err := a.open(opts); err != nil {
+			return nil, err
+		}
+		return a, nil
+	}
+
 	seedRatesJSON, err := os.Open(seedRatesFile)
 	if err != nil {
 		return nil, err
@@ -56,94 +168,237 @@ func NewAPI(seedRatesFile string) (*API, error) {
 	var ir IncomingRates
 	json.Unmarshal(bytes, &ir)
 
-	a := &API{}
 	a.Put(ir)
+	if err := a.open(opts); err != nil {
+		return nil, err
+	}
 
 	return a, nil
 }
 
+// load puts the rates of store in place. loaded is false when there is no store or it doesn't have any rates yet.
+func (a *API) load(store RateStore) (loaded bool, err error) {
+	if store == nil {
+		return false, nil
+	}
+	schedule, err := store.Load()
+	if err == ErrNoRates {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// Put the active rates and then the pending rates in the order they were scheduled in.
+	// Pending rates that have taken effect since they were stored replace the active rates.
+	for _, ir := range schedule {
+		if err := a.Put(ir); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// open restores the versions from the version log of opts once the rates have been loaded, and then
+// saves, audits and logs every set of rates that is accepted from then on
+func (a *API) open(opts Options) error {
+	if opts.Versions != nil {
+		if err := a.restoreVersions(opts.Versions); err != nil {
+			return err
+		}
+	}
+	a.store = opts.Store
+	a.audit = opts.Audit
+	a.versionLog = opts.Versions
+	return nil
+}
+
 // IncomingRates defines the json struct for new incoming rates
+// AllowOverlap is the policy used to resolve overlapping rates. It can be "lowest" or "highest".
+// Overlapping rates are rejected when no policy is given.
+// Author and Comment are optional and are recorded with the version of the rates.
+// EffectiveFrom is optional and schedules the rates to take effect at a later time.
+// Caller is the authenticated client that put the rates. It is only recorded in the audit log
+// and is never read from or written to the body.
 type IncomingRates struct {
-	Rates []RateDetail `json:"rates"`
+	Rates         []RateDetail `json:"rates" xml:"rate"`
+	AllowOverlap  string       `json:"allow_overlap,omitempty" xml:"allow_overlap,omitempty"`
+	Author        string       `json:"author,omitempty" xml:"author,omitempty"`
+	Comment       string       `json:"comment,omitempty" xml:"comment,omitempty"`
+	EffectiveFrom *time.Time   `json:"effective_from,omitempty" xml:"effective_from,omitempty"`
+	Overrides     []Override   `json:"overrides,omitempty" xml:"override,omitempty"`
+	Caller        Caller       `json:"-" xml:"-"`
 }
 
 // RateDetail holds the rate details of the new incoming rates
+// ID identifies the rate across puts so that it can be patched. Rates that are put without one are given one.
+// EffectiveUntil is optional and is the time the rate stops being in force
+// Pricing is optional and charges the rate by the duration of the time range instead of the flat Price
 type RateDetail struct {
-	Days  string `json:"days"`
-	Times string `json:"times"`
-	TZ    string `json:"tz"`
-	Price int    `json:"price"`
+	ID             string     `json:"id,omitempty" xml:"id,omitempty"`
+	Days           string     `json:"days" xml:"days"`
+	Times          string     `json:"times" xml:"times"`
+	TZ             string     `json:"tz" xml:"tz"`
+	Price          int        `json:"price" xml:"price"`
+	EffectiveUntil *time.Time `json:"effective_until,omitempty" xml:"effective_until,omitempty"`
+	Pricing        *Pricing   `json:"pricing,omitempty" xml:"pricing,omitempty"`
+	Boundary       string     `json:"boundary,omitempty" xml:"boundary,omitempty"`
 }
 
 // Put creates a new rate map with key of days
 func (a *API) Put(ir IncomingRates) error {
+	_, err := a.put(ir)
+	return err
+}
+
+// put creates a new rate map with key of days and returns the version it was recorded as
+func (a *API) put(ir IncomingRates) (Version, error) {
+	t, err := newTable(ir)
+	if err != nil {
+		return Version{}, err
+	}
+
+	// Lock it with a mutex so that no other update comes in before the tables are swapped
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.apply(t, ir)
+}
+
+// apply puts the table t that was built from ir in place and records it as a new version.
+// The caller must hold a.mu.
+func (a *API) apply(t *rateTable, ir IncomingRates) (Version, error) {
+	// The table isn't shared yet, so its rates can still be given ids
+	a.assignIDs(t.rates.Rates)
+	rates := t.rates
+	now := time.Now()
+	t.effectiveFrom = effectiveFrom(ir, now)
+	current := a.snapshot()
+	tables := schedule(current, t, now)
+	// Save the rates before they are put in place, so that the active rates are never ahead of the store
+	if a.store != nil {
+		if err := a.store.Save(scheduledRates(tables)); err != nil {
+			return Version{}, err
+		}
+	}
+	v := Version{
+		ID:            len(a.versions) + 1,
+		CreatedAt:     time.Now().UTC(),
+		IncomingRates: rates,
+	}
+	// Audit the rates before they are put in place as well, so that no change is ever applied without
+	// a record of it. The store is put back to the current rates if the record can't be appended.
+	if a.audit != nil {
+		if err := a.audit.Append(a.auditRecord(v, t, ir.Caller)); err != nil {
+			if a.store != nil {
+				a.store.Save(scheduledRates(current))
+			}
+			return Version{}, err
+		}
+	}
+	// The version is logged last, so that a version is never restored without the rates that it records
+	if a.versionLog != nil {
+		if err := a.versionLog.Append(v); err != nil {
+			if a.store != nil {
+				a.store.Save(scheduledRates(current))
+			}
+			return Version{}, err
+		}
+	}
+	a.tables.Store(tables)
+	// Record the accepted rates as a new version
+	a.versions = append(a.versions, v)
+	return v.copy(), nil
+}
+
+// assignIDs gives every rate without an id the next numeric id. Numeric ids that rates already have
+// are skipped, so the ids stay unique among the rates. The caller must hold a.mu.
+func (a *API) assignIDs(rates []RateDetail) {
+	a.reserveIDs(rates)
+	for i := range rates {
+		if rates[i].ID == "" {
+			a.lastID++
+			rates[i].ID = strconv.Itoa(a.lastID)
+		}
+	}
+}
+
+// reserveIDs makes sure that the numeric ids that rates already have are never given to other rates.
+// The caller must hold a.mu.
+func (a *API) reserveIDs(rates []RateDetail) {
+	for _, r := range rates {
+		if id, err := strconv.Atoi(r.ID); err == nil && id > a.lastID {
+			a.lastID = id
+		}
+	}
+}
+
+// effectiveFrom returns the time that rates put at now take effect. Rates with an effective_from
+// in the future are kept pending. All other rates take effect immediately, which is the zero time.
+func effectiveFrom(ir IncomingRates, now time.Time) time.Time {
+	if ir.EffectiveFrom != nil && ir.EffectiveFrom.After(now) {
+		return *ir.EffectiveFrom
+	}
+	return time.Time{}
+}
+
+// newTable validates ir and builds the table of its rates, keyed by weekday
+// The rates are rejected if any of them are malformed or, without a policy to resolve it, overlap.
+func newTable(ir IncomingRates) (*rateTable, error) {
 	// When the new rates are received, the map is built out with the key of days
 	// This let's the service quickly shortlist the rates that could be applicable for a given time range.
 	// Instead of a map, an immutable trie could also have been used - https://github.com/hashicorp/go-immutable-radix
-	// All times are stored as UTC
+	// All times are stored as wall clock times in the timezone of the rate. They are only resolved
+	// against an actual date when a rate is requested, so that daylight saving time is accounted for.
+
+	if !validOverlapPolicy(ir.AllowOverlap) {
+		return nil, fmt.Errorf("unknown overlap policy: %s", ir.AllowOverlap)
+	}
+	// Reject malformed rates before any of them are processed
+	if err := Validate(ir); err != nil {
+		return nil, err
+	}
 
 	// m will contain the new rate map.
 	m := make(map[string][]DayRate)
+	// locations holds every distinct timezone that the new rates are defined in
+	var locations []*time.Location
 
 	// Iterate over the new rates and process them
-	for _, r := range ir.Rates {
+	for i, r := range ir.Rates {
 		// Split the time range and establish a start time and end time
-		timeRange := strings.Split(r.Times, "-")
-		startTime, err := strconv.Atoi(timeRange[0])
+		startTime, endTime, err := parseTimes(r.Times)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		startTimeHours := startTime / 100 // Hours
-		startTimeMins := startTime % 100  // Minutes
-		endTime, err := strconv.Atoi(timeRange[1])
+		loc, err := time.LoadLocation(r.TZ)
 		if err != nil {
-			return err
-		}
-		endTimeHours := endTime / 100 // Hours
-		endTimeMins := endTime % 100  // Minutes
-		if err != nil {
-			return err
+			return nil, err
 		}
+		locations = appendLocation(locations, loc)
 		// Iterate over all the days in an input rate detail and make entries in
 		// the map based on the key of the weekday
 		for _, day := range strings.Split(r.Days, ",") {
 			properWeekdayName, ok := dayMap[day]
 			if !ok {
-				return fmt.Errorf("abbreviated day not present: %s", day)
-			}
-			// build time with localized timezone that's present in the input
-			localizedTime, err := TimeIn(time.Now(), r.TZ)
-			if err != nil {
-				return err
-			}
-			for {
-				s := localizedTime.Weekday().String()
-				if s != properWeekdayName {
-					localizedTime = localizedTime.AddDate(0, 0, 1) // Increment by one day until we hit the desired weekday
-					continue
-				}
-				break
+				return nil, fmt.Errorf("abbreviated day not present: %s", day)
 			}
 
-			// Get UTC times of the corresponding localized times
-			utcStartTime := time.Date(localizedTime.Year(), localizedTime.Month(), localizedTime.Day(), startTimeHours, startTimeMins, 0, 0, localizedTime.Location()).UTC()
-			utcEndTime := time.Date(localizedTime.Year(), localizedTime.Month(), localizedTime.Day(), endTimeHours, endTimeMins, 0, 0, localizedTime.Location()).UTC()
-			// Get 2400 layout times of the corresponding UTC times
-			armyStartTime := a.armyTime(utcStartTime)
-			armyEndTime := a.armyTime(utcEndTime)
-			// if armyEndTime > 2400, then it starts back from 0. We need to add 2400
-			// to maintain the contiguous time block for time comparisons
-			if armyEndTime < armyStartTime {
-				armyEndTime += 2400
-			}
-
-			// Populate struct with time range and rate for a specific weekday
+			// Populate struct with the wall clock time range and rate for a specific weekday
 			dr := DayRate{
-				day:       utcStartTime.Weekday().String(),
-				startTime: float32(armyStartTime),
-				endTime:   float32(armyEndTime),
+				day:       properWeekdayName,
+				startTime: startTime,
+				endTime:   endTime,
 				price:     r.Price,
-				tz:        "UTC",
+				tz:        r.TZ,
+				loc:       loc,
+				index:     i,
+				boundary:  r.Boundary,
+			}
+			if r.EffectiveUntil != nil {
+				dr.effectiveUntil = *r.EffectiveUntil
+			}
+			if r.Pricing != nil {
+				pricing := *r.Pricing
+				dr.pricing = &pricing
 			}
 			// Check if there is an existing key of the weekday in the map
 			v, ok := m[properWeekdayName]
@@ -157,54 +412,343 @@ func (a *API) Put(ir IncomingRates) error {
 			m[properWeekdayName] = []DayRate{dr}
 		}
 	}
-	// Lock it with a mutex before swapping the maps
-	a.mu.Lock()
-	a.rateMap = m
-	a.mu.Unlock()
+	// Reject the rates if any of them overlap, unless there is a policy to resolve the overlap
+	if ir.AllowOverlap == "" {
+		if conflicts := findConflicts(m); len(conflicts) > 0 {
+			return nil, &OverlapError{Conflicts: conflicts}
+		}
+	}
+
+	// Keep a copy of the original input so that the active rates can be read back
+	// in the same shape that they were received in
+	rates := copyRates(ir)
+	rates.Caller = Caller{}
+
+	overrides, err := newOverrides(ir.Overrides)
+	if err != nil {
+		return nil, err
+	}
+	// The time range is also resolved in the timezone of every override
+	for _, o := range overrides {
+		locations = appendLocation(locations, o.loc)
+	}
+
+	t := &rateTable{
+		rateMap:      m,
+		index:        indexRates(m),
+		overrides:    overrides,
+		locations:    locations,
+		allowOverlap: ir.AllowOverlap,
+		rates:        rates,
+	}
+	return t, nil
+}
+
+// auditRecord returns the audit record of putting the table t as the version v by caller.
+// The caller must hold a.mu.
+func (a *API) auditRecord(v Version, t *rateTable, caller Caller) AuditRecord {
+	previous := replacedRates(a.snapshot(), t, v.CreatedAt)
+	diff := diffRates(previous, v.IncomingRates)
+	return AuditRecord{
+		Time:       v.CreatedAt,
+		FacilityID: a.facility,
+		Caller:     caller.Name,
+		Role:       caller.Role,
+		Version:    v.ID,
+		Previous:   previous,
+		New:        copyRates(v.IncomingRates),
+		Diff:       diff.Days,
+		Overrides:  diff.Overrides,
+	}
+}
+
+// schedule returns tables with t added to them in the order that they take effect
+func schedule(tables []*rateTable, t *rateTable, now time.Time) []*rateTable {
+	var scheduled []*rateTable
+	for _, existing := range tables {
+		inForce := !existing.effectiveFrom.After(now)
+		// A table that takes effect immediately replaces every table that is already in force
+		if t.effectiveFrom.IsZero() && inForce {
+			continue
+		}
+		// A pending table replaces the pending table that takes effect at the same time
+		if !inForce && existing.effectiveFrom.Equal(t.effectiveFrom) {
+			continue
+		}
+		scheduled = append(scheduled, existing)
+	}
+	// Insert the new table after every table that takes effect before it
+	i := sort.Search(len(scheduled), func(i int) bool {
+		return scheduled[i].effectiveFrom.After(t.effectiveFrom)
+	})
+	scheduled = append(scheduled, nil)
+	copy(scheduled[i+1:], scheduled[i:])
+	scheduled[i] = t
+	return scheduled
+}
+
+// scheduledRates returns the rates of each of the tables as they were received by Put
+func scheduledRates(tables []*rateTable) []IncomingRates {
+	rates := make([]IncomingRates, len(tables))
+	for i, t := range tables {
+		rates[i] = t.rates
+	}
+	return rates
+}
+
+// tableAt returns the table that is in force at tm. It returns nil if no table is in force at tm.
+func (a *API) tableAt(tm time.Time) *rateTable {
+	return tableAt(a.snapshot(), tm)
+}
+
+// snapshot returns the current tables without taking the lock. Put stores a new slice instead of
+// modifying it, so the snapshot stays consistent for as long as it is used.
+func (a *API) snapshot() []*rateTable {
+	// The tables are nil until the first rates are put
+	tables, _ := a.tables.Load().([]*rateTable)
+	return tables
+}
+
+// tableAt returns the table among tables that is in force at tm. It returns nil if no table is in force at tm.
+func tableAt(tables []*rateTable, tm time.Time) *rateTable {
+	for i := len(tables) - 1; i >= 0; i-- {
+		if !tables[i].effectiveFrom.After(tm) {
+			return tables[i]
+		}
+	}
 	return nil
 }
 
+// List returns the currently active rates as they were received by Put
+func (a *API) List() IncomingRates {
+	t := a.tableAt(time.Now())
+	if t == nil {
+		return IncomingRates{Rates: []RateDetail{}}
+	}
+	return copyRates(t.rates)
+}
+
+// Pending returns the rates that are scheduled to take effect in the future, in the order they take effect
+func (a *API) Pending() []IncomingRates {
+	now := time.Now()
+	pending := []IncomingRates{}
+	for _, t := range a.snapshot() {
+		if t.effectiveFrom.After(now) {
+			pending = append(pending, copyRates(t.rates))
+		}
+	}
+	return pending
+}
+
+// copyRates returns a copy of ir that doesn't share its rates with the original
+func copyRates(ir IncomingRates) IncomingRates {
+	rates := ir
+	rates.Rates = make([]RateDetail, len(ir.Rates))
+	copy(rates.Rates, ir.Rates)
+	if ir.Overrides != nil {
+		rates.Overrides = make([]Override, len(ir.Overrides))
+		copy(rates.Overrides, ir.Overrides)
+	}
+	return rates
+}
+
 // Get returns the rate of parking for a given time range
 func (a *API) Get(p ParkingTimesRequest) (rate int, err error) {
+	q, err := a.Quote(p)
+	if err != nil {
+		return 0, err
+	}
+	return q.Rate, nil
+}
+
+// Quote returns the rate of parking for a given time range along with the
+// per-day segments it was priced from when the request spans multiple days.
+// The time range is priced by the rates that are in force at its start time.
+func (a *API) Quote(p ParkingTimesRequest) (Quote, error) {
+	return a.quote(a.snapshot(), p)
+}
+
+// quote prices the time range against the table among tables that is in force at its start time
+func (a *API) quote(tables []*rateTable, p ParkingTimesRequest) (Quote, error) {
+	if !validStitch(p.Stitch) {
+		return Quote{}, fmt.Errorf("unknown stitch mode: %s", p.Stitch)
+	}
+	t := tableAt(tables, p.StartTime)
+	if t == nil {
+		return Quote{}, errors.New("unavailable")
+	}
+	// daily holds what each rate has charged on each day, so that its daily maximum is applied across segments
+	daily := dailyCharges{}
+	// By default a time range has to be contained by a single window
+	price := func(start, end time.Time, offset time.Duration) (int, []Window, error) {
+		rate, err := a.dayRate(t, start, end, offset, daily)
+		return rate, nil, err
+	}
+	if p.Stitch != "" {
+		price = func(start, end time.Time, offset time.Duration) (int, []Window, error) {
+			return a.stitchRate(t, p.Stitch, start, end, offset, daily)
+		}
+	}
+	if p.MultiDay {
+		return a.multiDayQuote(price, t.locations, p)
+	}
 	// Rates will not span multiple days
 	if p.StartTime.Day() != p.EndTime.Day() || p.StartTime.Month() != p.EndTime.Month() || p.StartTime.Year() != p.EndTime.Year() {
-		return 0, errors.New("unavailable")
+		return Quote{}, errors.New("unavailable")
 	}
-	// Transform localized time to UTC time
-	utcStart := p.StartTime.UTC()
-	utcEnd := p.EndTime.UTC()
-	// Get UTC army time (2400 hour layout)
-	startHours := a.armyTime(utcStart)
-	endHours := a.armyTime(utcEnd)
+	rate, windows, err := price(p.StartTime, p.EndTime, 0)
+	if err != nil {
+		return Quote{}, err
+	}
+	return Quote{Rate: rate, Windows: windows}, nil
+}
 
-	// Get the rates for the specific weekday
-	weekday := p.StartTime.Weekday().String()
-	rates, ok := a.rateMap[weekday]
-	if !ok {
-		return 0, errors.New("unavailable")
+// multiDayQuote breaks the time range up at every midnight in the timezones of the rates,
+// which are given by locations, and prices each of the resulting segments against the rates
+// of its weekday. The segments are the same whichever timezone the request is made in.
+// The quote is unavailable if any one of the segments is unavailable.
+func (a *API) multiDayQuote(price pricer, locations []*time.Location, p ParkingTimesRequest) (Quote, error) {
+	if !p.EndTime.After(p.StartTime) {
+		return Quote{}, errors.New("unavailable")
+	}
+	// Without any rates, the time range is broken up at every midnight in the timezone of the request
+	if len(locations) == 0 {
+		locations = []*time.Location{p.StartTime.Location()}
+	}
+	var q Quote
+	start := p.StartTime
+	for start.Before(p.EndTime) {
+		// The segment ends at the next midnight in any of the timezones or at the end of the request,
+		// whichever comes first. It is kept in the timezone of the request.
+		end := p.EndTime
+		for _, loc := range locations {
+			local := start.In(loc)
+			midnight := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+			if midnight.Before(end) {
+				end = midnight.In(p.StartTime.Location())
+			}
+		}
+		rate, windows, err := price(start, end, start.Sub(p.StartTime))
+		if err != nil {
+			return Quote{}, err
+		}
+		q.Rate += rate
+		q.Windows = append(q.Windows, windows...)
+		q.Segments = append(q.Segments, Segment{
+			StartTime: start,
+			EndTime:   end,
+			Rate:      rate,
+		})
+		start = end
 	}
+	return q, nil
+}
 
-	// Check if the parking time range is contained within the defined ranges of rates
-	for _, r := range rates {
-		if startHours >= r.startTime && endHours <= r.endTime {
-			return r.price, nil
+// dayRate returns the rate for a time range that lies within the weekday of its start time.
+// The end time may fall on the following midnight. offset is how long the car has already been
+// parked for before start when the time range is a segment of a longer time range, and daily holds
+// what the rates have already charged on the days of the earlier segments. The charge is added to it.
+func (a *API) dayRate(t *rateTable, start, end time.Time, offset time.Duration, daily dailyCharges) (int, error) {
+	// price, rate and found hold the best matching rate when overlapping rates are resolved by a policy
+	var price int
+	var rate DayRate
+	found := false
+	// matches holds the rates that contain the time range in each timezone
+	var matches [8]*DayRate
+	// While an override is in force, only the rates of the overrides price the time range
+	overridden := len(t.overrides) > 0 && t.overridden(start, end)
+
+	// The time range is resolved to wall clock times in every timezone that rates are defined in.
+	// This takes the offset of the timezone on the requested date into account, so a rate of
+	// 0900-2100 always means 9am to 9pm regardless of daylight saving time.
+	for _, loc := range t.locations {
+		localStart := start.In(loc)
+		localEnd := end.In(loc)
+		// Get the wall clock times as seconds since midnight in the timezone of the rates
+		startSeconds, endSeconds, ok := a.wallClock(localStart, localEnd)
+		if !ok {
+			continue
+		}
+
+		// Get the rates for the specific weekday in this timezone, unless an override of the date takes precedence
+		weekday := localStart.Weekday().String()
+		tree := t.index[indexKey{day: weekday, tz: loc.String()}]
+		if o := t.overrideOn(localStart, loc); o != nil {
+			tree = o.tree
+		} else if overridden {
+			continue
+		}
+
+		// Check if the parking time range is contained within the defined ranges of rates
+		for _, r := range tree.containing(startSeconds, endSeconds, matches[:0]) {
+			// The boundary of the window decides if an instant at its start or end is covered
+			if !r.covers(startSeconds, endSeconds) {
+				continue
+			}
+			// Skip the rate if it stops being in force before the end of the time range
+			if !r.effectiveUntil.IsZero() && end.After(r.effectiveUntil) {
+				continue
+			}
+			charge := r.charge(start, end, offset, daily)
+			// Without a policy the rates can't overlap, so the first match is the only match
+			if t.allowOverlap == "" {
+				daily.add(*r, start, charge)
+				return charge, nil
+			}
+			if !found || preferredPrice(t.allowOverlap, charge, price) {
+				price = charge
+				rate = *r
+				found = true
+			}
 		}
 	}
+	if found {
+		daily.add(rate, start, price)
+		return price, nil
+	}
 
 	// Return error of unavailable when the parking time range was not found among the rates
 	return 0, errors.New("unavailable")
 }
 
-// armyTime returns a 2400 layout time in UTC timezone
-func (a *API) armyTime(tm time.Time) float32 {
-	// armyTime is a 24 hour clock in the format: 1240 this means the clock time is 12:40pm
-	tm = tm.UTC() // Get UTC time
+// wallClock returns the wall clock times of a time range that lies within a single day as seconds since midnight.
+// A fraction of a second at the end is rounded up, so the time range is never priced by a window that ends before it.
+// An end time on the following midnight is returned as the end of the day. ok is false when the time range spans
+// more than a single day.
+func (a *API) wallClock(start, end time.Time) (startSeconds, endSeconds int, ok bool) {
+	startSeconds = secondsOfDay(start)
+	endSeconds = secondsOfDay(end)
+	if end.Nanosecond() > 0 {
+		endSeconds++
+	}
+
+	y1, m1, d1 := start.Date()
+	y2, m2, d2 := end.Date()
+	if y1 == y2 && m1 == m2 && d1 == d2 {
+		return startSeconds, endSeconds, true
+	}
+	// Check if the end time is the midnight that follows the start time
+	nextMidnight := time.Date(y1, m1, d1+1, 0, 0, 0, 0, start.Location())
+	if end.Equal(nextMidnight) {
+		return startSeconds, endOfDay, true
+	}
+	return 0, 0, false
+}
+
+// secondsOfDay returns the whole seconds since midnight of the wall clock time of tm in its timezone
+func secondsOfDay(tm time.Time) int {
 	h, min, sec := tm.Clock()
-	minAsHours := float32(min)
-	secAsHours := float32(sec) / 3600
-	armyTime := float32(h)*100 + minAsHours + secAsHours
+	return h*3600 + min*60 + sec
+}
 
-	return armyTime
+// appendLocation appends loc to locations if a location with the same name isn't already present
+func appendLocation(locations []*time.Location, loc *time.Location) []*time.Location {
+	for _, l := range locations {
+		if l.String() == loc.String() {
+			return locations
+		}
+	}
+	return append(locations, loc)
 }
 
 // TimeIn returns the time in UTC if the name is "" or "UTC".
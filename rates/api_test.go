@@ -2,6 +2,7 @@ package rates
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -12,7 +13,7 @@ func TestNewAPI(t *testing.T) {
 	a, err := NewAPI("seed_rates.json")
 	assert.Nil(t, err)
 	assert.NotNil(t, a)
-	assert.NotNil(t, a.rateMap)
+	assert.NotNil(t, a.tableAt(time.Now()).rateMap)
 }
 
 func TestPut(t *testing.T) {
@@ -29,27 +30,58 @@ func TestPut(t *testing.T) {
 	a, err := NewAPI("seed_rates.json")
 	assert.Nil(t, err)
 	// assert that the ratemap is seeded during instantiation
-	assert.NotNil(t, a.rateMap)
+	assert.NotNil(t, a.tableAt(time.Now()).rateMap)
 	a.Put(ir)
 
-	assert.NotNil(t, a.rateMap)
-	mondayRate := a.rateMap["Monday"]
-	// the expected rate has been transformed to UTC time
+	assert.NotNil(t, a.tableAt(time.Now()).rateMap)
+	mondayRate := a.tableAt(time.Now()).rateMap["Monday"]
+	loc, err := time.LoadLocation("America/Chicago")
+	assert.Nil(t, err)
+	// the expected rate is kept as wall clock time in the timezone of the rate
 	expectedMondayDayRate := []DayRate{DayRate{
 		day:       "Monday",
-		startTime: float32(1400),
-		endTime:   float32(2600),
+		startTime: 9 * 3600,
+		endTime:   21 * 3600,
 		price:     1500,
-		tz:        "UTC"},
+		tz:        "America/Chicago",
+		loc:       loc},
 	}
 	assert.Equal(t, expectedMondayDayRate, mondayRate)
 	assert.Equal(t, expectedMondayDayRate[0].endTime, mondayRate[0].endTime)
 
 	// assert that the new rate was put in place. sunday is not present in the new rate
-	_, ok := a.rateMap["sun"]
+	_, ok := a.tableAt(time.Now()).rateMap["sun"]
 	assert.False(t, ok)
 }
 
+func TestList(t *testing.T) {
+	rd := RateDetail{
+		ID:    "weekdays",
+		Days:  "mon,tues,thurs",
+		Times: "0900-2100",
+		TZ:    "America/Chicago",
+		Price: 1500,
+	}
+	ir := IncomingRates{
+		Rates: []RateDetail{rd},
+	}
+
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	// assert that the seeded rates can be read back
+	assert.Len(t, a.List().Rates, 5)
+
+	err = a.Put(ir)
+	assert.Nil(t, err)
+	// assert that the rates are returned as they were put, not as their UTC equivalents
+	assert.Equal(t, ir, a.List())
+
+	// assert that modifying the returned rates does not modify the active rates
+	listed := a.List()
+	listed.Rates[0].Price = 1
+	assert.Equal(t, 1500, a.List().Rates[0].Price)
+}
+
 func TestGetRate(t *testing.T) {
 	a, err := NewAPI("seed_rates.json")
 	assert.Nil(t, err)
@@ -115,6 +147,444 @@ func TestGetRate(t *testing.T) {
 	}
 }
 
+func TestGetRateAcrossDaylightSavingTime(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+
+	// America/Chicago springs forward on 2020-03-08 at 2am and falls back on 2020-11-01 at 2am
+	loc, e := time.LoadLocation("America/Chicago")
+	assert.Nil(t, e)
+	testCases := []struct {
+		name string
+		p    ParkingTimesRequest
+		rate int
+		err  error
+	}{
+		{
+			name: "Standard time, UTC request",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 1, 5, 15, 0, 0, 0, time.UTC), // 9am CST
+				EndTime:   time.Date(2020, 1, 5, 21, 0, 0, 0, time.UTC), // 3pm CST
+			},
+			rate: 2000,
+			err:  nil,
+		},
+		{
+			name: "Standard time, UTC request before the rate starts",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 1, 5, 14, 30, 0, 0, time.UTC), // 8:30am CST
+				EndTime:   time.Date(2020, 1, 5, 15, 30, 0, 0, time.UTC), // 9:30am CST
+			},
+			rate: 0,
+			err:  errors.New("unavailable"),
+		},
+		{
+			name: "Daylight saving time, UTC request",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 7, 5, 14, 0, 0, 0, time.UTC), // 9am CDT
+				EndTime:   time.Date(2020, 7, 5, 20, 0, 0, 0, time.UTC), // 3pm CDT
+			},
+			rate: 2000,
+			err:  nil,
+		},
+		{
+			name: "Daylight saving time, UTC request that is before the rate starts in standard time",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 7, 5, 14, 30, 0, 0, time.UTC), // 9:30am CDT
+				EndTime:   time.Date(2020, 7, 5, 15, 30, 0, 0, time.UTC), // 10:30am CDT
+			},
+			rate: 2000,
+			err:  nil,
+		},
+		{
+			name: "Day before spring forward",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 3, 7, 9, 0, 0, 0, loc),
+				EndTime:   time.Date(2020, 3, 7, 21, 0, 0, 0, loc),
+			},
+			rate: 2000,
+			err:  nil,
+		},
+		{
+			name: "Spring forward, across the transition",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 3, 8, 7, 30, 0, 0, time.UTC), // 1:30am CST
+				EndTime:   time.Date(2020, 3, 8, 9, 0, 0, 0, time.UTC),  // 4am CDT
+			},
+			rate: 925,
+			err:  nil,
+		},
+		{
+			name: "Spring forward, after the transition",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 3, 8, 9, 0, 0, 0, loc),
+				EndTime:   time.Date(2020, 3, 8, 21, 0, 0, 0, loc),
+			},
+			rate: 2000,
+			err:  nil,
+		},
+		{
+			name: "Spring forward, past the end of the rate in wall clock time",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 3, 8, 11, 30, 0, 0, time.UTC), // 6:30am CDT
+				EndTime:   time.Date(2020, 3, 8, 12, 30, 0, 0, time.UTC), // 7:30am CDT
+			},
+			rate: 0,
+			err:  errors.New("unavailable"),
+		},
+		{
+			name: "Fall back, across the transition",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 11, 1, 6, 30, 0, 0, time.UTC), // 1:30am CDT
+				EndTime:   time.Date(2020, 11, 1, 10, 0, 0, 0, time.UTC), // 4am CST
+			},
+			rate: 925,
+			err:  nil,
+		},
+		{
+			name: "Fall back, after the transition",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 11, 1, 9, 0, 0, 0, loc),
+				EndTime:   time.Date(2020, 11, 1, 21, 0, 0, 0, loc),
+			},
+			rate: 2000,
+			err:  nil,
+		},
+		{
+			name: "Fall back, past the end of the rate in wall clock time",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 11, 2, 2, 30, 0, 0, time.UTC), // 8:30pm CST
+				EndTime:   time.Date(2020, 11, 2, 3, 30, 0, 0, time.UTC), // 9:30pm CST
+			},
+			rate: 0,
+			err:  errors.New("unavailable"),
+		},
+	}
+	for _, tt := range testCases {
+		rate, err := a.Get(tt.p)
+		assert.Equal(t, tt.err, err, tt.name)
+		assert.Equal(t, tt.rate, rate, tt.name)
+	}
+}
+
+func TestQuoteMultiDay(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{
+			{Days: "fri,sat", Times: "0000-2400", TZ: "UTC", Price: 2000},
+			{Days: "sun", Times: "0000-1200", TZ: "UTC", Price: 1000},
+		},
+	})
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		name     string
+		p        ParkingTimesRequest
+		rate     int
+		segments []Segment
+		err      error
+	}{
+		{
+			name: "Spans midnight",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 4, 20, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 5, 10, 0, 0, 0, time.UTC),
+				MultiDay:  true,
+			},
+			rate: 3000,
+			segments: []Segment{
+				{
+					StartTime: time.Date(2020, 4, 4, 20, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 5, 0, 0, 0, 0, time.UTC),
+					Rate:      2000,
+				},
+				{
+					StartTime: time.Date(2020, 4, 5, 0, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 5, 10, 0, 0, 0, time.UTC),
+					Rate:      1000,
+				},
+			},
+			err: nil,
+		},
+		{
+			name: "Spans several days",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 3, 8, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 5, 12, 0, 0, 0, time.UTC),
+				MultiDay:  true,
+			},
+			rate: 5000,
+			segments: []Segment{
+				{
+					StartTime: time.Date(2020, 4, 3, 8, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 4, 0, 0, 0, 0, time.UTC),
+					Rate:      2000,
+				},
+				{
+					StartTime: time.Date(2020, 4, 4, 0, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 5, 0, 0, 0, 0, time.UTC),
+					Rate:      2000,
+				},
+				{
+					StartTime: time.Date(2020, 4, 5, 0, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 5, 12, 0, 0, 0, time.UTC),
+					Rate:      1000,
+				},
+			},
+			err: nil,
+		},
+		{
+			name: "Fails when one of the days is unavailable",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 4, 20, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 5, 13, 0, 0, 0, time.UTC),
+				MultiDay:  true,
+			},
+			rate:     0,
+			segments: nil,
+			err:      errors.New("unavailable"),
+		},
+		{
+			name: "Fails when the end time is before the start time",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 5, 10, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 4, 20, 0, 0, 0, time.UTC),
+				MultiDay:  true,
+			},
+			rate:     0,
+			segments: nil,
+			err:      errors.New("unavailable"),
+		},
+	}
+	for _, tt := range testCases {
+		q, err := a.Quote(tt.p)
+		assert.Equal(t, tt.err, err, tt.name)
+		assert.Equal(t, tt.rate, q.Rate, tt.name)
+		assert.Equal(t, tt.segments, q.Segments, tt.name)
+	}
+}
+
+func TestQuoteMultiDayInAnyTimezone(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{{Days: "mon,tues,wed,thurs,fri,sat,sun", Times: "0000-2400", TZ: "America/Chicago", Price: 10}},
+	})
+	assert.Nil(t, err)
+	loc, err := time.LoadLocation("America/Chicago")
+	assert.Nil(t, err)
+
+	// The same time span is split at midnight in Chicago whichever timezone it is sent in
+	for _, tz := range []*time.Location{time.UTC, loc} {
+		q, err := a.Quote(ParkingTimesRequest{
+			StartTime: time.Date(2020, 4, 6, 20, 0, 0, 0, time.UTC).In(tz),
+			EndTime:   time.Date(2020, 4, 7, 4, 0, 0, 0, time.UTC).In(tz),
+			MultiDay:  true,
+		})
+		assert.Nil(t, err, tz.String())
+		assert.Equal(t, 10, q.Rate, tz.String())
+		assert.Len(t, q.Segments, 1, tz.String())
+		assert.Equal(t, tz, q.Segments[0].StartTime.Location(), tz.String())
+	}
+
+	// Rates in several timezones are split at the midnight of each of them, here at midnight in UTC
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon", Times: "1200-2400", TZ: "UTC", Price: 10},
+			{Days: "tues", Times: "0900-1500", TZ: "Asia/Tokyo", Price: 20},
+		},
+	})
+	assert.Nil(t, err)
+	q, err := a.Quote(ParkingTimesRequest{
+		StartTime: time.Date(2020, 4, 6, 15, 0, 0, 0, loc),
+		EndTime:   time.Date(2020, 4, 6, 23, 0, 0, 0, loc),
+		MultiDay:  true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 30, q.Rate)
+	assert.Equal(t, []Segment{
+		{StartTime: time.Date(2020, 4, 6, 15, 0, 0, 0, loc), EndTime: time.Date(2020, 4, 6, 19, 0, 0, 0, loc), Rate: 10},
+		{StartTime: time.Date(2020, 4, 6, 19, 0, 0, 0, loc), EndTime: time.Date(2020, 4, 6, 23, 0, 0, 0, loc), Rate: 20},
+	}, q.Segments)
+}
+
+// nextWeekday returns the first time after from that falls on weekday at the given hour in loc
+func nextWeekday(from time.Time, weekday time.Weekday, hour int, loc *time.Location) time.Time {
+	from = from.In(loc)
+	for d := 1; ; d++ {
+		tm := time.Date(from.Year(), from.Month(), from.Day()+d, hour, 0, 0, 0, loc)
+		if tm.Weekday() == weekday {
+			return tm
+		}
+	}
+}
+
+func TestScheduledRates(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	loc, err := time.LoadLocation("America/Chicago")
+	assert.Nil(t, err)
+
+	effectiveFrom := time.Now().AddDate(0, 0, 30)
+	pending := IncomingRates{
+		Rates: []RateDetail{
+			{ID: "daily", Days: "mon,tues,wed,thurs,fri,sat,sun", Times: "0000-2400", TZ: "America/Chicago", Price: 3000},
+		},
+		EffectiveFrom: &effectiveFrom,
+	}
+	err = a.Put(pending)
+	assert.Nil(t, err)
+
+	// assert that the pending rates are kept aside and the seed rates are still active
+	assert.Len(t, a.List().Rates, 5)
+	assert.Equal(t, []IncomingRates{pending}, a.Pending())
+
+	// a Monday morning before and after the pending rates take effect
+	beforeMonday := nextWeekday(time.Now(), time.Monday, 10, loc)
+	afterMonday := nextWeekday(effectiveFrom, time.Monday, 10, loc)
+	p := ParkingTimesRequest{StartTime: beforeMonday, EndTime: beforeMonday.Add(time.Hour)}
+	rate, err := a.Get(p)
+	assert.Nil(t, err)
+	assert.Equal(t, 1500, rate)
+	p = ParkingTimesRequest{StartTime: afterMonday, EndTime: afterMonday.Add(time.Hour)}
+	rate, err = a.Get(p)
+	assert.Nil(t, err)
+	assert.Equal(t, 3000, rate)
+
+	// assert that rates which take effect immediately replace the active rates, but not the pending rates
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1000},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, a.List().Rates, 1)
+	assert.Len(t, a.Pending(), 1)
+	p = ParkingTimesRequest{StartTime: beforeMonday, EndTime: beforeMonday.Add(time.Hour)}
+	rate, err = a.Get(p)
+	assert.Nil(t, err)
+	assert.Equal(t, 1000, rate)
+	p = ParkingTimesRequest{StartTime: afterMonday, EndTime: afterMonday.Add(time.Hour)}
+	rate, err = a.Get(p)
+	assert.Nil(t, err)
+	assert.Equal(t, 3000, rate)
+
+	// assert that pending rates replace the pending rates that take effect at the same time
+	pending.Rates[0].Price = 3500
+	err = a.Put(pending)
+	assert.Nil(t, err)
+	assert.Equal(t, []IncomingRates{pending}, a.Pending())
+	rate, err = a.Get(p)
+	assert.Nil(t, err)
+	assert.Equal(t, 3500, rate)
+
+	// assert that pending rates are kept in the order they take effect
+	earlier := effectiveFrom.AddDate(0, 0, -10)
+	earlierPending := IncomingRates{
+		Rates: []RateDetail{
+			{ID: "monday", Days: "mon", Times: "0000-2400", TZ: "America/Chicago", Price: 2500},
+		},
+		EffectiveFrom: &earlier,
+	}
+	err = a.Put(earlierPending)
+	assert.Nil(t, err)
+	assert.Equal(t, []IncomingRates{earlierPending, pending}, a.Pending())
+
+	// assert that rates with an effective_from in the past take effect immediately
+	past := time.Now().AddDate(0, 0, -1)
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon", Times: "0900-1000", TZ: "America/Chicago", Price: 500},
+		},
+		EffectiveFrom: &past,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 500, a.List().Rates[0].Price)
+	assert.Len(t, a.Pending(), 2)
+}
+
+func TestEffectiveUntil(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+
+	until := time.Date(2020, 4, 10, 0, 0, 0, 0, time.UTC)
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon", Times: "0900-2100", TZ: "UTC", Price: 1500, EffectiveUntil: &until},
+		},
+	})
+	assert.Nil(t, err)
+
+	// Monday 2020-04-06 is before the rate expires, Monday 2020-04-13 is after
+	p := ParkingTimesRequest{
+		StartTime: time.Date(2020, 4, 6, 10, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2020, 4, 6, 11, 0, 0, 0, time.UTC),
+	}
+	rate, err := a.Get(p)
+	assert.Nil(t, err)
+	assert.Equal(t, 1500, rate)
+
+	p = ParkingTimesRequest{
+		StartTime: time.Date(2020, 4, 13, 10, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2020, 4, 13, 11, 0, 0, 0, time.UTC),
+	}
+	rate, err = a.Get(p)
+	assert.Equal(t, errors.New("unavailable"), err)
+	assert.Equal(t, 0, rate)
+}
+
+func TestGetRateWithSeconds(t *testing.T) {
+	a := &API{}
+	err := a.Put(IncomingRates{
+		Rates: []RateDetail{
+			{Days: "sat", Times: "09:00:00-17:00:30", TZ: "America/Chicago", Price: 1500},
+			{Days: "sat", Times: "17:00:30-2100", TZ: "America/Chicago", Price: 2000},
+		},
+	})
+	assert.Nil(t, err)
+	loc, err := time.LoadLocation("America/Chicago")
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+		rate  int
+		err   error
+	}{
+		{
+			name:  "Ends on the last second of the window",
+			start: time.Date(2020, 4, 4, 9, 0, 0, 0, loc),
+			end:   time.Date(2020, 4, 4, 17, 0, 30, 0, loc),
+			rate:  1500,
+		},
+		{
+			name:  "Ends a second after the window",
+			start: time.Date(2020, 4, 4, 9, 0, 0, 0, loc),
+			end:   time.Date(2020, 4, 4, 17, 0, 31, 0, loc),
+			err:   errors.New("unavailable"),
+		},
+		{
+			name:  "Ends a fraction of a second after the window",
+			start: time.Date(2020, 4, 4, 9, 0, 0, 0, loc),
+			end:   time.Date(2020, 4, 4, 17, 0, 30, 1, loc),
+			err:   errors.New("unavailable"),
+		},
+		{
+			name:  "Starts on the first second of the window",
+			start: time.Date(2020, 4, 4, 17, 0, 30, 0, loc),
+			end:   time.Date(2020, 4, 4, 21, 0, 0, 0, loc),
+			rate:  2000,
+		},
+	}
+	for _, tt := range testCases {
+		rate, err := a.Get(ParkingTimesRequest{StartTime: tt.start, EndTime: tt.end})
+		assert.Equal(t, tt.err, err, tt.name)
+		assert.Equal(t, tt.rate, rate, tt.name)
+	}
+}
+
 func TestGetRateWhenNoRatePresent(t *testing.T) {
 	a, err := NewAPI("seed_rates.json")
 	assert.Nil(t, err)
@@ -129,15 +599,108 @@ func TestGetRateWhenNoRatePresent(t *testing.T) {
 	assert.Equal(t, 0, rate)
 }
 
-func TestArmytime(t *testing.T) {
-	a, err := NewAPI("seed_rates.json")
-	assert.Nil(t, err)
-
+func TestSecondsOfDay(t *testing.T) {
 	tm := time.Date(2020, 4, 4, 12, 15, 0, 0, time.UTC)
-	armyTime := a.armyTime(tm)
-	assert.Equal(t, float32(1215), armyTime)
+	assert.Equal(t, 44100, secondsOfDay(tm))
 
 	tm = time.Date(2020, 4, 4, 12, 15, 23, 101, time.UTC)
-	armyTime = a.armyTime(tm)
-	assert.Equal(t, float32(1215.0063), armyTime)
+	assert.Equal(t, 44123, secondsOfDay(tm))
+}
+
+func TestWallClock(t *testing.T) {
+	a := &API{}
+	testCases := []struct {
+		name       string
+		start, end time.Time
+		startSec   int
+		endSec     int
+		ok         bool
+	}{
+		{
+			name:     "Whole seconds",
+			start:    time.Date(2020, 4, 4, 9, 0, 0, 0, time.UTC),
+			end:      time.Date(2020, 4, 4, 17, 30, 15, 0, time.UTC),
+			startSec: 32400,
+			endSec:   63015,
+			ok:       true,
+		},
+		{
+			name:     "A fraction of a second at the end is rounded up",
+			start:    time.Date(2020, 4, 4, 9, 0, 0, 500, time.UTC),
+			end:      time.Date(2020, 4, 4, 20, 59, 59, 500, time.UTC),
+			startSec: 32400,
+			endSec:   75600,
+			ok:       true,
+		},
+		{
+			name:     "Ends on the following midnight",
+			start:    time.Date(2020, 4, 4, 23, 0, 0, 0, time.UTC),
+			end:      time.Date(2020, 4, 5, 0, 0, 0, 0, time.UTC),
+			startSec: 82800,
+			endSec:   86400,
+			ok:       true,
+		},
+		{
+			name:  "Spans more than a single day",
+			start: time.Date(2020, 4, 4, 23, 0, 0, 0, time.UTC),
+			end:   time.Date(2020, 4, 5, 0, 0, 1, 0, time.UTC),
+		},
+	}
+	for _, tt := range testCases {
+		start, end, ok := a.wallClock(tt.start, tt.end)
+		assert.Equal(t, tt.ok, ok, tt.name)
+		assert.Equal(t, tt.startSec, start, tt.name)
+		assert.Equal(t, tt.endSec, end, tt.name)
+	}
+}
+
+// minuteRates returns rates with a window of every step minutes of every day of the week in UTC,
+// which is 7*1440/step windows
+func minuteRates(step int) []RateDetail {
+	var rates []RateDetail
+	for m := 0; m < 1440; m += step {
+		end := m + step
+		rates = append(rates, RateDetail{
+			Days:  "mon,tues,wed,thurs,fri,sat,sun",
+			Times: fmt.Sprintf("%02d%02d-%02d%02d", m/60, m%60, end/60, end%60),
+			TZ:    "UTC",
+			Price: 1000 + m,
+		})
+	}
+	return rates
+}
+
+// benchmarkGet prices half minute time ranges spread over a day against the rates
+func benchmarkGet(b *testing.B, ir IncomingRates) {
+	a := &API{}
+	if err := a.Put(ir); err != nil {
+		b.Fatal(err)
+	}
+	requests := make([]ParkingTimesRequest, 1440)
+	for m := range requests {
+		start := time.Date(2020, 4, 8, 0, m, 0, 0, time.UTC)
+		requests[m] = ParkingTimesRequest{StartTime: start, EndTime: start.Add(30 * time.Second)}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := a.Get(requests[i%len(requests)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	for _, step := range []int{60, 5, 1} {
+		ir := IncomingRates{Rates: minuteRates(step)}
+		b.Run(fmt.Sprintf("%d windows", 7*1440/step), func(b *testing.B) {
+			benchmarkGet(b, ir)
+		})
+	}
+}
+
+func BenchmarkGetOverlapping(b *testing.B) {
+	// Every minute is covered by a one minute window, an hourly window and a window of the whole day
+	rates := append(minuteRates(1), minuteRates(60)...)
+	rates = append(rates, minuteRates(1440)...)
+	benchmarkGet(b, IncomingRates{Rates: rates, AllowOverlap: OverlapLowest})
 }
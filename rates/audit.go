@@ -0,0 +1,123 @@
+package rates
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrNoAuditLog is returned when the audit log is requested from an API that doesn't keep one
+var ErrNoAuditLog = errors.New("audit log is not enabled")
+
+// AuditRecord is an entry in the audit log of a set of rates that was accepted by Put
+// FacilityID is the id of the facility that the rates were put for, and is empty for the rates at /rates.
+// Previous holds the rates that the new rates replaced. Diff holds the windows and Overrides holds the overrides
+// that changed between them.
+type AuditRecord struct {
+	Time       time.Time      `json:"time"`
+	FacilityID string         `json:"facility_id,omitempty"`
+	Caller     string         `json:"caller,omitempty"`
+	Role       string         `json:"role,omitempty"`
+	Version    int            `json:"version"`
+	Previous   IncomingRates  `json:"previous"`
+	New        IncomingRates  `json:"new"`
+	Diff       []DayDiff      `json:"diff"`
+	Overrides  []OverrideDiff `json:"overrides,omitempty"`
+}
+
+// FileAuditLog is an AuditLog that appends every record to a file as a line of JSON
+type FileAuditLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileAuditLog returns a FileAuditLog that appends records to the file at path
+// The file is created when the first record is appended
+func NewFileAuditLog(path string) *FileAuditLog {
+	return &FileAuditLog{path: path}
+}
+
+// Append writes the record to the end of the file. The file is never truncated or rewritten.
+func (f *FileAuditLog) Append(r AuditRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return appendLine(f.path, r)
+}
+
+// appendLine writes v as a line of JSON to the end of the file at path, creating the file if it doesn't exist
+func appendLine(path string, v interface{}) error {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	bytes = append(bytes, '\n')
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(bytes); err != nil {
+		file.Close()
+		return err
+	}
+	// Flush the line to disk before the rates that it records are put in place
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Since reads the records from since onwards from the file, oldest first
+func (f *FileAuditLog) Since(since time.Time) ([]AuditRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records := []AuditRecord{}
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+	for {
+		var r AuditRecord
+		err := dec.Decode(&r)
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !r.Time.Before(since) {
+			records = append(records, r)
+		}
+	}
+}
+
+// Audit returns the audit records of the rates that were accepted from since onwards, oldest first
+// The audit log can be shared by several facilities, so only the records of the rates of the API are returned.
+// It returns ErrNoAuditLog when the API doesn't keep an audit log
+func (a *API) Audit(since time.Time) ([]AuditRecord, error) {
+	if a.audit == nil {
+		return nil, ErrNoAuditLog
+	}
+	records, err := a.audit.Since(since)
+	if err != nil {
+		return nil, err
+	}
+	own := []AuditRecord{}
+	for _, r := range records {
+		if r.FacilityID == a.facility {
+			own = append(own, r)
+		}
+	}
+	return own, nil
+}
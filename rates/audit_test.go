@@ -0,0 +1,197 @@
+package rates
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	l := NewFileAuditLog(filepath.Join(dir, "audit.jsonl"))
+	records, err := l.Since(time.Time{})
+	assert.Nil(t, err)
+	assert.Empty(t, records)
+
+	first := AuditRecord{
+		Time:     time.Date(2020, 4, 6, 9, 0, 0, 0, time.UTC),
+		Caller:   "ops",
+		Role:     RoleAdmin,
+		Version:  2,
+		Previous: IncomingRates{Rates: []RateDetail{}},
+		New:      storedRates,
+		Diff:     diffRates(IncomingRates{}, storedRates).Days,
+	}
+	second := first
+	second.Time = first.Time.Add(time.Hour)
+	second.Version = 3
+	assert.Nil(t, l.Append(first))
+	assert.Nil(t, l.Append(second))
+
+	records, err = l.Since(time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, []AuditRecord{first, second}, records)
+
+	// assert that only the records from since onwards are returned
+	records, err = l.Since(second.Time)
+	assert.Nil(t, err)
+	assert.Equal(t, []AuditRecord{second}, records)
+
+	// assert that a new log appends to the records that are already in the file
+	l = NewFileAuditLog(filepath.Join(dir, "audit.jsonl"))
+	assert.Nil(t, l.Append(second))
+	records, err = l.Since(time.Time{})
+	assert.Nil(t, err)
+	assert.Len(t, records, 3)
+}
+
+func TestAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	a, err := NewAPIWithOptions("seed_rates.json", Options{Audit: NewFileAuditLog(filepath.Join(dir, "audit.jsonl"))})
+	assert.Nil(t, err)
+	seed := a.List()
+
+	// assert that loading the seed rates isn't audited
+	records, err := a.Audit(time.Time{})
+	assert.Nil(t, err)
+	assert.Empty(t, records)
+
+	before := time.Now().UTC()
+	ir := IncomingRates{
+		Rates: []RateDetail{
+			{ID: "weekdays", Days: "mon,tues,thurs", Times: "0900-2100", TZ: "America/Chicago", Price: 1750},
+		},
+		Author: "pricing",
+		Caller: Caller{Name: "ops", Role: RoleAdmin},
+	}
+	assert.Nil(t, a.Put(ir))
+	// assert that rejected rates aren't audited
+	assert.NotNil(t, a.Put(IncomingRates{Rates: []RateDetail{{Days: "mon", Times: "0900", TZ: "UTC"}}}))
+	_, err = a.Rollback(1, "pricing", Caller{Name: "oncall", Role: RoleAdmin})
+	assert.Nil(t, err)
+
+	records, err = a.Audit(before)
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+
+	put := records[0]
+	assert.Equal(t, "ops", put.Caller)
+	assert.Equal(t, RoleAdmin, put.Role)
+	assert.Equal(t, 2, put.Version)
+	assert.False(t, put.Time.Before(before))
+	assert.Equal(t, seed, put.Previous)
+	ir.Caller = Caller{}
+	assert.Equal(t, ir, put.New)
+	// The seed rates charge 1500 for 0900-2100 on Monday, which only changes in price, and 1000 for 0100-0500
+	assert.Len(t, put.Diff, 7)
+	assert.Equal(t, DayDiff{
+		Day:     "Monday",
+		Removed: []RateDetail{{ID: "4", Days: "mon", Times: "0100-0500", TZ: "America/Chicago", Price: 1000}},
+		Changed: []Change{{
+			Before: RateDetail{ID: "1", Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+			After:  RateDetail{ID: "weekdays", Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1750},
+		}},
+	}, put.Diff[0])
+
+	rollback := records[1]
+	assert.Equal(t, "oncall", rollback.Caller)
+	assert.Equal(t, 3, rollback.Version)
+	assert.Equal(t, put.New, rollback.Previous)
+	assert.Equal(t, seed.Rates, rollback.New.Rates)
+	assert.Equal(t, "rollback to version 1", rollback.New.Comment)
+
+	// assert that the caller isn't kept with the rates or their versions
+	assert.Equal(t, Caller{}, a.List().Caller)
+	v, err := a.Version(2)
+	assert.Nil(t, err)
+	assert.Equal(t, Caller{}, v.Caller)
+
+	records, err = a.Audit(time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Empty(t, records)
+}
+
+func TestAuditPending(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	a, err := NewAPIWithOptions("seed_rates.json", Options{Audit: NewFileAuditLog(filepath.Join(dir, "audit.jsonl"))})
+	assert.Nil(t, err)
+	effectiveFrom := time.Now().Add(24 * time.Hour)
+	pending := IncomingRates{
+		Rates:         []RateDetail{{ID: "saturday", Days: "sat", Times: "0000-2400", TZ: "UTC", Price: 900}},
+		EffectiveFrom: &effectiveFrom,
+	}
+	assert.Nil(t, a.Put(pending))
+	replacement := pending
+	replacement.Rates = []RateDetail{{ID: "saturday", Days: "sat", Times: "0000-2400", TZ: "UTC", Price: 1200}}
+	assert.Nil(t, a.Put(replacement))
+
+	records, err := a.Audit(time.Time{})
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+	// assert that pending rates are compared against the rates they replace when they take effect
+	assert.Equal(t, a.List(), records[0].Previous)
+	assert.Equal(t, pending.Rates, records[1].Previous.Rates)
+	assert.Equal(t, []DayDiff{{
+		Day: "Saturday",
+		Changed: []Change{{
+			Before: RateDetail{ID: "saturday", Days: "sat", Times: "0000-2400", TZ: "UTC", Price: 900},
+			After:  RateDetail{ID: "saturday", Days: "sat", Times: "0000-2400", TZ: "UTC", Price: 1200},
+		}},
+	}}, records[1].Diff)
+}
+
+func TestAuditOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	a, err := NewAPIWithOptions("seed_rates.json", Options{Audit: NewFileAuditLog(filepath.Join(dir, "audit.jsonl"))})
+	assert.Nil(t, err)
+	christmas := Override{Name: "Christmas", Annual: "12-25", TZ: "America/Chicago", Rates: []OverrideRate{}}
+	_, err = a.Patch(RatePatch{Overrides: []Override{christmas}})
+	assert.Nil(t, err)
+
+	records, err := a.Audit(time.Time{})
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+	// assert that adding only an override is recorded in the overrides of the diff
+	assert.Empty(t, records[0].Diff)
+	assert.Equal(t, []OverrideDiff{{Name: "Christmas", Dates: "every 12-25", After: &christmas}}, records[0].Overrides)
+}
+
+func TestAuditFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// The audit log can't be appended to when its path is a directory
+	a, err := NewAPIWithOptions("seed_rates.json", Options{Audit: NewFileAuditLog(dir)})
+	assert.Nil(t, err)
+	seed := a.List()
+
+	// assert that the rates aren't put in place when they can't be audited
+	err = a.Put(IncomingRates{Rates: []RateDetail{{Days: "mon", Times: "0900-1700", TZ: "UTC", Price: 1500}}})
+	assert.NotNil(t, err)
+	assert.Equal(t, seed, a.List())
+	assert.Len(t, a.Versions(), 1)
+}
+
+func TestAuditDisabled(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	_, err = a.Audit(time.Time{})
+	assert.Equal(t, ErrNoAuditLog, err)
+}
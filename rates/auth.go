@@ -0,0 +1,169 @@
+package rates
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	// RoleQuote can only get quotes for time ranges
+	RoleQuote = "quote"
+	// RoleAdmin can manage the rates and facilities in addition to getting quotes
+	RoleAdmin = "admin"
+)
+
+// callerKey is the key that the authenticated caller is stored under in the gin context
+const callerKey = "rates.caller"
+
+// Caller is the identity of an authenticated client and the role it was granted
+type Caller struct {
+	Name string
+	Role string
+}
+
+// Auth authenticates clients by API key or by a JWT signed with an HMAC secret
+// Clients send an API key in the X-API-Key header or a JWT in the Authorization header as a Bearer token.
+// The JWT has to carry the name of the client in its sub claim and its role in a role claim.
+type Auth struct {
+	// APIKeys holds the caller of each API key
+	APIKeys map[string]Caller
+	// JWTSecret is the secret that JWTs are signed with. JWTs are rejected when it is empty.
+	JWTSecret []byte
+}
+
+// ParseAPIKeys parses API keys in the format name:role:key separated by commas
+func ParseAPIKeys(keys string) (map[string]Caller, error) {
+	apiKeys := make(map[string]Caller)
+	for i, entry := range strings.Split(keys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		// The entry isn't part of the error, since it could be the key itself
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("api key %d must be in the format name:role:key", i+1)
+		}
+		if !validRole(parts[1]) {
+			return nil, fmt.Errorf("unknown role for api key %s: %s", parts[0], parts[1])
+		}
+		apiKeys[parts[2]] = Caller{Name: parts[0], Role: parts[1]}
+	}
+	return apiKeys, nil
+}
+
+// validRole checks if role is one of the supported roles
+func validRole(role string) bool {
+	return role == RoleQuote || role == RoleAdmin
+}
+
+// jwtClaims are the claims of a JWT that are used to identify the caller
+type jwtClaims struct {
+	Role string `json:"role"`
+	jwt.StandardClaims
+}
+
+// authenticate returns the caller that an API key or the value of an Authorization header belongs to
+func (a *Auth) authenticate(key, header string) (Caller, error) {
+	if key != "" {
+		// Every key is compared in constant time so that the time taken doesn't give away a key
+		var caller Caller
+		found := false
+		for k, cl := range a.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				caller = cl
+				found = true
+			}
+		}
+		if !found {
+			return Caller{}, errors.New("invalid api key")
+		}
+		return caller, nil
+	}
+
+	if header == "" {
+		return Caller{}, errors.New("missing credentials")
+	}
+	if !strings.HasPrefix(header, "Bearer ") || len(a.JWTSecret) == 0 {
+		return Caller{}, errors.New("invalid credentials")
+	}
+	var claims jwtClaims
+	_, err := jwt.ParseWithClaims(strings.TrimPrefix(header, "Bearer "), &claims, func(t *jwt.Token) (interface{}, error) {
+		// Only HMAC signatures are accepted, so a token can't pick another algorithm to be verified with
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %s", t.Method.Alg())
+		}
+		return a.JWTSecret, nil
+	})
+	if err != nil {
+		return Caller{}, fmt.Errorf("invalid token: %s", err)
+	}
+	if claims.Subject == "" || !validRole(claims.Role) {
+		return Caller{}, errors.New("invalid token: the sub and role claims are required")
+	}
+	// A token without an expiry would be valid forever, so it is rejected
+	if claims.ExpiresAt == 0 {
+		return Caller{}, errors.New("invalid token: the exp claim is required")
+	}
+	return Caller{Name: claims.Subject, Role: claims.Role}, nil
+}
+
+// Require returns a middleware that only lets callers with one of the roles through
+// Requests without valid credentials are rejected with a 401 and callers with any other role with a 403.
+// Admins are let through everywhere. A nil Auth lets every request through.
+func (a *Auth) Require(roles ...string) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		if a == nil {
+			c.Next()
+			return
+		}
+		caller, err := a.authenticate(c.GetHeader("X-API-Key"), c.GetHeader("Authorization"))
+		if err != nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(401, PutResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if !caller.allowed(roles) {
+			c.AbortWithStatusJSON(403, PutResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("role %s is not allowed to %s %s", caller.Role, c.Request.Method, c.FullPath()),
+			})
+			return
+		}
+		c.Set(callerKey, caller)
+		c.Next()
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// allowed checks if the caller has one of the roles. Admins are allowed everything.
+func (c Caller) allowed(roles []string) bool {
+	if c.Role == RoleAdmin {
+		return true
+	}
+	for _, role := range roles {
+		if c.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CallerFrom returns the caller that was authenticated for the request
+// It returns false when the request wasn't authenticated
+func CallerFrom(c *gin.Context) (Caller, bool) {
+	v, ok := c.Get(callerKey)
+	if !ok {
+		return Caller{}, false
+	}
+	caller, ok := v.(Caller)
+	return caller, ok
+}
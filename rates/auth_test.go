@@ -0,0 +1,198 @@
+package rates
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/theblueskies/spothro/rates/ratespb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var testSecret = []byte("test-secret")
+
+// signToken returns a JWT for the claims signed with the secret
+func signToken(t *testing.T, method jwt.SigningMethod, secret interface{}, claims jwtClaims) string {
+	token, err := jwt.NewWithClaims(method, claims).SignedString(secret)
+	assert.Nil(t, err)
+	return token
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys("booking:quote:abc123, ops:admin:def:456,")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]Caller{
+		"abc123":  {Name: "booking", Role: RoleQuote},
+		"def:456": {Name: "ops", Role: RoleAdmin},
+	}, keys)
+
+	_, err = ParseAPIKeys("booking:quote:abc123,abc456")
+	assert.EqualError(t, err, "api key 2 must be in the format name:role:key")
+	_, err = ParseAPIKeys("booking:root:abc123")
+	assert.EqualError(t, err, "unknown role for api key booking: root")
+
+	keys, err = ParseAPIKeys("")
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestAuth(t *testing.T) {
+	auth := &Auth{
+		APIKeys: map[string]Caller{
+			"quote-key": {Name: "booking", Role: RoleQuote},
+			"admin-key": {Name: "ops", Role: RoleAdmin},
+		},
+		JWTSecret: testSecret,
+	}
+	adminToken := signToken(t, jwt.SigningMethodHS256, testSecret, jwtClaims{
+		Role:           RoleAdmin,
+		StandardClaims: jwt.StandardClaims{Subject: "pricing", ExpiresAt: time.Now().Add(time.Hour).Unix()},
+	})
+	quoteToken := signToken(t, jwt.SigningMethodHS512, testSecret, jwtClaims{
+		Role:           RoleQuote,
+		StandardClaims: jwt.StandardClaims{Subject: "booking", ExpiresAt: time.Now().Add(time.Hour).Unix()},
+	})
+	expiredToken := signToken(t, jwt.SigningMethodHS256, testSecret, jwtClaims{
+		Role:           RoleAdmin,
+		StandardClaims: jwt.StandardClaims{Subject: "pricing", ExpiresAt: time.Now().Add(-time.Hour).Unix()},
+	})
+	wrongSecretToken := signToken(t, jwt.SigningMethodHS256, []byte("another-secret"), jwtClaims{
+		Role:           RoleAdmin,
+		StandardClaims: jwt.StandardClaims{Subject: "pricing"},
+	})
+	unsignedToken := signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, jwtClaims{
+		Role:           RoleAdmin,
+		StandardClaims: jwt.StandardClaims{Subject: "pricing"},
+	})
+	noRoleToken := signToken(t, jwt.SigningMethodHS256, testSecret, jwtClaims{
+		StandardClaims: jwt.StandardClaims{Subject: "pricing", ExpiresAt: time.Now().Add(time.Hour).Unix()},
+	})
+	noExpiryToken := signToken(t, jwt.SigningMethodHS256, testSecret, jwtClaims{
+		Role:           RoleAdmin,
+		StandardClaims: jwt.StandardClaims{Subject: "pricing"},
+	})
+
+	rateQuery := "/rate?start_time=2015-07-01T07:00:00-05:00&end_time=2015-07-01T12:00:00-05:00"
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		headers       map[string]string
+		outStatusCode int
+		outMessage    string
+	}{
+		{name: "health is open", method: "GET", path: "/health", outStatusCode: 200, outMessage: "rates app online"},
+		{name: "missing credentials", method: "GET", path: rateQuery, outStatusCode: 401, outMessage: "missing credentials"},
+		{name: "unknown api key", method: "GET", path: rateQuery, headers: map[string]string{"X-API-Key": "guess"}, outStatusCode: 401, outMessage: "invalid api key"},
+		{name: "basic auth", method: "GET", path: rateQuery, headers: map[string]string{"Authorization": "Basic b3BzOmFkbWlu"}, outStatusCode: 401, outMessage: "invalid credentials"},
+		{name: "quote key gets a rate", method: "GET", path: rateQuery, headers: map[string]string{"X-API-Key": "quote-key"}, outStatusCode: 200, outMessage: "success retrieving rate"},
+		{name: "quote key can't put rates", method: "PUT", path: "/rates", headers: map[string]string{"X-API-Key": "quote-key"}, outStatusCode: 403, outMessage: "role quote is not allowed to PUT /rates"},
+		{name: "quote key can't list versions", method: "GET", path: "/rates/versions", headers: map[string]string{"X-API-Key": "quote-key"}, outStatusCode: 403, outMessage: "role quote is not allowed to GET /rates/versions"},
+		{name: "admin key puts rates", method: "PUT", path: "/rates", headers: map[string]string{"X-API-Key": "admin-key"}, outStatusCode: 200, outMessage: "Successfully updated rates"},
+		{name: "admin key gets a rate", method: "GET", path: rateQuery, headers: map[string]string{"X-API-Key": "admin-key"}, outStatusCode: 200, outMessage: "success retrieving rate"},
+		{name: "admin token puts rates", method: "PUT", path: "/rates", headers: map[string]string{"Authorization": "Bearer " + adminToken}, outStatusCode: 200, outMessage: "Successfully updated rates"},
+		{name: "quote token gets a rate", method: "GET", path: rateQuery, headers: map[string]string{"Authorization": "Bearer " + quoteToken}, outStatusCode: 200, outMessage: "success retrieving rate"},
+		{name: "quote token can't put rates", method: "PUT", path: "/rates", headers: map[string]string{"Authorization": "Bearer " + quoteToken}, outStatusCode: 403, outMessage: "role quote is not allowed to PUT /rates"},
+		{name: "expired token", method: "PUT", path: "/rates", headers: map[string]string{"Authorization": "Bearer " + expiredToken}, outStatusCode: 401, outMessage: "invalid token: token is expired by "},
+		{name: "token signed with another secret", method: "PUT", path: "/rates", headers: map[string]string{"Authorization": "Bearer " + wrongSecretToken}, outStatusCode: 401, outMessage: "invalid token: signature is invalid"},
+		{name: "unsigned token", method: "PUT", path: "/rates", headers: map[string]string{"Authorization": "Bearer " + unsignedToken}, outStatusCode: 401, outMessage: "invalid token: unexpected signing method: none"},
+		{name: "token without a role", method: "PUT", path: "/rates", headers: map[string]string{"Authorization": "Bearer " + noRoleToken}, outStatusCode: 401, outMessage: "invalid token: the sub and role claims are required"},
+		{name: "token without an expiry", method: "PUT", path: "/rates", headers: map[string]string{"Authorization": "Bearer " + noExpiryToken}, outStatusCode: 401, outMessage: "invalid token: the exp claim is required"},
+	}
+
+	for _, tt := range testCases {
+		m := &mockService{rate: 1750}
+		r := NewRouterWithOptions(m, RouterOptions{Auth: auth})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(`{"rates": []}`))
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		r.ServeHTTP(w, req)
+
+		var b PutResponse
+		err := json.Unmarshal(w.Body.Bytes(), &b)
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, tt.outStatusCode, w.Code, tt.name)
+		// The message of an expired token ends with how long ago it expired
+		assert.True(t, strings.HasPrefix(b.Message, tt.outMessage), tt.name, b.Message)
+		if tt.outStatusCode == 401 {
+			assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"), tt.name)
+		}
+		// The service is never called when the request is rejected
+		if tt.outStatusCode == 401 || tt.outStatusCode == 403 {
+			assert.Equal(t, 0, m.putCallCount+m.getCallCount, tt.name)
+		}
+		// The authenticated caller is passed on with the rates to be recorded in the audit log
+		if tt.method == "PUT" && tt.outStatusCode == 200 {
+			assert.NotEmpty(t, m.lastRates.Caller.Name, tt.name)
+			assert.Equal(t, RoleAdmin, m.lastRates.Caller.Role, tt.name)
+		}
+	}
+}
+
+func TestGRPCAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	a, err := NewAPIWithOptions("seed_rates.json", Options{Audit: NewFileAuditLog(filepath.Join(dir, "audit.jsonl"))})
+	assert.Nil(t, err)
+	auth := &Auth{
+		APIKeys: map[string]Caller{
+			"quote-key": {Name: "booking", Role: RoleQuote},
+		},
+		JWTSecret: testSecret,
+	}
+	adminToken := signToken(t, jwt.SigningMethodHS256, testSecret, jwtClaims{
+		Role:           RoleAdmin,
+		StandardClaims: jwt.StandardClaims{Subject: "pricing", ExpiresAt: time.Now().Add(time.Hour).Unix()},
+	})
+
+	lisClient, stop := newGRPCClientWithServer(t, NewGRPCServerWithAuth(a, auth))
+	defer stop()
+	getRate := &ratespb.GetRateRequest{
+		StartTime: timestamppb.New(time.Date(2020, 4, 3, 14, 30, 0, 0, time.UTC)),
+		EndTime:   timestamppb.New(time.Date(2020, 4, 3, 19, 30, 0, 0, time.UTC)),
+	}
+
+	_, err = lisClient.GetRate(context.Background(), getRate)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	quoteCtx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "quote-key")
+	resp, err := lisClient.GetRate(quoteCtx, getRate)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2000), resp.Rate)
+
+	_, err = lisClient.PutRates(quoteCtx, &ratespb.PutRatesRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	stream, err := lisClient.ListRates(quoteCtx, &ratespb.ListRatesRequest{})
+	assert.Nil(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	adminCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+adminToken)
+	_, err = lisClient.PutRates(adminCtx, &ratespb.PutRatesRequest{
+		Rates: []*ratespb.RateDetail{{Days: "mon", Times: "0900-1700", Tz: "UTC", Price: 1500}},
+	})
+	assert.Nil(t, err)
+
+	// assert that the caller of the token is recorded in the audit log
+	records, err := a.Audit(time.Time{})
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "pricing", records[0].Caller)
+	assert.Equal(t, RoleAdmin, records[0].Role)
+}
@@ -0,0 +1,23 @@
+package rates
+
+// MaxBatchQuotes is the largest number of time ranges that can be quoted in a single batch
+const MaxBatchQuotes = 1000
+
+// QuoteResult holds the quote for one of the time ranges of a batch or the reason it couldn't be quoted
+type QuoteResult struct {
+	Quote Quote
+	Err   error
+}
+
+// QuoteBatch quotes every time range and returns the results in the same order.
+// Every time range is priced against the same snapshot of the rates, so a Put that
+// happens while the batch is quoted affects either all or none of the results.
+func (a *API) QuoteBatch(ps []ParkingTimesRequest) []QuoteResult {
+	tables := a.snapshot()
+	results := make([]QuoteResult, len(ps))
+	for i, p := range ps {
+		q, err := a.quote(tables, p)
+		results[i] = QuoteResult{Quote: q, Err: err}
+	}
+	return results
+}
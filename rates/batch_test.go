@@ -0,0 +1,66 @@
+package rates
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteBatch(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+
+	results := a.QuoteBatch([]ParkingTimesRequest{
+		{
+			StartTime: time.Date(2020, 4, 3, 14, 30, 0, 0, time.UTC),
+			EndTime:   time.Date(2020, 4, 3, 19, 30, 0, 0, time.UTC),
+		},
+		{
+			StartTime: time.Date(2020, 4, 4, 7, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2020, 4, 4, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			StartTime: time.Date(2020, 4, 3, 14, 30, 0, 0, time.UTC),
+			EndTime:   time.Date(2020, 4, 3, 19, 30, 0, 0, time.UTC),
+			Stitch:    "min",
+		},
+		{
+			StartTime: time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2020, 4, 1, 13, 0, 0, 0, time.UTC),
+		},
+	})
+	// The results are in the same order as the time ranges
+	assert.Equal(t, []QuoteResult{
+		{Quote: Quote{Rate: 2000}},
+		{Err: errors.New("unavailable")},
+		{Err: errors.New("unknown stitch mode: min")},
+		{Quote: Quote{Rate: 1750}},
+	}, results)
+
+	assert.Equal(t, []QuoteResult{}, a.QuoteBatch(nil))
+}
+
+func TestQuoteUsesSnapshot(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	p := ParkingTimesRequest{
+		StartTime: time.Date(2020, 4, 3, 14, 30, 0, 0, time.UTC),
+		EndTime:   time.Date(2020, 4, 3, 19, 30, 0, 0, time.UTC),
+	}
+
+	tables := a.snapshot()
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{{Days: "fri", Times: "0000-2400", TZ: "UTC", Price: 3000}},
+	})
+	assert.Nil(t, err)
+
+	// The snapshot that was taken before the Put still prices by the previous rates
+	q, err := a.quote(tables, p)
+	assert.Nil(t, err)
+	assert.Equal(t, 2000, q.Rate)
+	q, err = a.Quote(p)
+	assert.Nil(t, err)
+	assert.Equal(t, 3000, q.Rate)
+}
@@ -0,0 +1,54 @@
+package rates
+
+const (
+	// BoundaryHalfOpen includes the start of the window but not its end, which is the default
+	BoundaryHalfOpen = "half_open"
+	// BoundaryOpenStart includes the end of the window but not its start
+	BoundaryOpenStart = "open_start"
+	// BoundaryClosed includes both the start and the end of the window
+	BoundaryClosed = "closed"
+	// BoundaryOpen includes neither the start nor the end of the window
+	BoundaryOpen = "open"
+)
+
+// validBoundary checks if boundary is one of the supported boundaries of a window
+func validBoundary(boundary string) bool {
+	switch boundary {
+	case "", BoundaryHalfOpen, BoundaryOpenStart, BoundaryClosed, BoundaryOpen:
+		return true
+	}
+	return false
+}
+
+// boundaryOf returns the boundary of the window of r, which is half open when it isn't set
+func boundaryOf(r RateDetail) string {
+	if r.Boundary == "" {
+		return BoundaryHalfOpen
+	}
+	return r.Boundary
+}
+
+// includesStart checks if the instant that the window of the rate starts at belongs to the window
+func (r *DayRate) includesStart() bool {
+	return r.boundary == "" || r.boundary == BoundaryHalfOpen || r.boundary == BoundaryClosed
+}
+
+// includesEnd checks if the instant that the window of the rate ends at belongs to the window
+func (r *DayRate) includesEnd() bool {
+	return r.boundary == BoundaryOpenStart || r.boundary == BoundaryClosed
+}
+
+// covers checks if the rate prices the wall clock time range from start to end, in seconds since midnight.
+// A time range is covered when it lies within the window and the instant that it starts at belongs to the
+// window by its boundary, so a window that doesn't include its start never prices a time range from its start.
+// A time range that is a single instant is also only covered when the instant belongs to the window, so that
+// back to back windows never both cover the instant where one ends and the other starts.
+func (r *DayRate) covers(start, end int) bool {
+	if start < r.startTime || end > r.endTime {
+		return false
+	}
+	if start == r.startTime && !r.includesStart() {
+		return false
+	}
+	return start < end || start < r.endTime || r.includesEnd()
+}
@@ -0,0 +1,87 @@
+package rates
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCovers(t *testing.T) {
+	// window returns a rate from 0900 to 2100 with the boundary
+	window := func(boundary string) *DayRate {
+		return &DayRate{startTime: 9 * 3600, endTime: 21 * 3600, boundary: boundary}
+	}
+	testCases := []struct {
+		name       string
+		boundary   string
+		start, end int
+		covers     bool
+	}{
+		{"Time range within the window", "", 10 * 3600, 20 * 3600, true},
+		{"Time range from the start to the end of a half open window", "", 9 * 3600, 21 * 3600, true},
+		{"Time range from the start to the end of a closed window", BoundaryClosed, 9 * 3600, 21 * 3600, true},
+		{"Time range from the start of an open window", BoundaryOpen, 9 * 3600, 10 * 3600, false},
+		{"Time range from the start of a window with an open start", BoundaryOpenStart, 9 * 3600, 10 * 3600, false},
+		{"Time range up to the end of an open window", BoundaryOpen, 20 * 3600, 21 * 3600, true},
+		{"Time range just after the start of an open window", BoundaryOpen, 9*3600 + 1, 10 * 3600, true},
+		{"Time range past the end of the window", BoundaryClosed, 20 * 3600, 21*3600 + 1, false},
+		{"Time range before the start of the window", BoundaryClosed, 9*3600 - 1, 10 * 3600, false},
+		{"Start of a half open window", "", 9 * 3600, 9 * 3600, true},
+		{"End of a half open window", BoundaryHalfOpen, 21 * 3600, 21 * 3600, false},
+		{"Start of a window with an open start", BoundaryOpenStart, 9 * 3600, 9 * 3600, false},
+		{"End of a window with an open start", BoundaryOpenStart, 21 * 3600, 21 * 3600, true},
+		{"Start of a closed window", BoundaryClosed, 9 * 3600, 9 * 3600, true},
+		{"End of a closed window", BoundaryClosed, 21 * 3600, 21 * 3600, true},
+		{"Start of an open window", BoundaryOpen, 9 * 3600, 9 * 3600, false},
+		{"End of an open window", BoundaryOpen, 21 * 3600, 21 * 3600, false},
+		{"Instant within an open window", BoundaryOpen, 12 * 3600, 12 * 3600, true},
+	}
+	for _, tt := range testCases {
+		assert.Equal(t, tt.covers, window(tt.boundary).covers(tt.start, tt.end), tt.name)
+	}
+}
+
+func TestGetRateAtBoundary(t *testing.T) {
+	unavailable := errors.New("unavailable")
+	testCases := []struct {
+		name          string
+		first, second string
+		rate          int
+		err           error
+		// after and afterErr are the rate of the hour from the instant and its error
+		after    int
+		afterErr error
+	}{
+		{"The instant belongs to the window that starts at it by default", "", "", 2000, nil, 2000, nil},
+		{"The instant belongs to the window that includes its end", BoundaryOpenStart, BoundaryOpen, 1500, nil, 0, unavailable},
+		{"The instant belongs to neither window", BoundaryHalfOpen, BoundaryOpen, 0, unavailable, 0, unavailable},
+		{"The instant belongs to the closed window that starts at it", BoundaryHalfOpen, BoundaryClosed, 2000, nil, 2000, nil},
+	}
+	instant := time.Date(2020, 4, 4, 21, 0, 0, 0, time.UTC)
+	for _, tt := range testCases {
+		a := &API{}
+		err := a.Put(IncomingRates{
+			Rates: []RateDetail{
+				{Days: "sat", Times: "0900-2100", TZ: "UTC", Price: 1500, Boundary: tt.first},
+				{Days: "sat", Times: "2100-2300", TZ: "UTC", Price: 2000, Boundary: tt.second},
+			},
+		})
+		assert.Nil(t, err, tt.name)
+
+		// Back to back windows never both claim the instant where one ends and the other starts
+		rate, err := a.Get(ParkingTimesRequest{StartTime: instant, EndTime: instant})
+		assert.Equal(t, tt.err, err, tt.name)
+		assert.Equal(t, tt.rate, rate, tt.name)
+
+		// A time range that ends at the instant is priced by the window that it lies within, while a time range
+		// that starts at the instant is only priced by the window that it lies within if the instant belongs to it
+		rate, err = a.Get(ParkingTimesRequest{StartTime: instant.Add(-time.Hour), EndTime: instant})
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, 1500, rate, tt.name)
+		rate, err = a.Get(ParkingTimesRequest{StartTime: instant, EndTime: instant.Add(time.Hour)})
+		assert.Equal(t, tt.afterErr, err, tt.name)
+		assert.Equal(t, tt.after, rate, tt.name)
+	}
+}
@@ -0,0 +1,96 @@
+package rates
+
+import (
+	"sort"
+	"time"
+)
+
+// CalendarInterval is a priced wall clock time range of a day in the timezone of the calendar
+// Start and End are written as HH:MM, or as HH:MM:SS when they fall on a particular second.
+// Index is the index of the rate in the rates that were put, or -1 for a rate of the Override with that name
+type CalendarInterval struct {
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Index    int      `json:"index"`
+	Override string   `json:"override,omitempty"`
+	Price    int      `json:"price"`
+	Pricing  *Pricing `json:"pricing,omitempty"`
+}
+
+// Gap is a wall clock time range of a day in the timezone of the calendar that no rate covers
+type Gap struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// CalendarDay holds the priced intervals of a day ordered by their start and the gaps between them
+type CalendarDay struct {
+	Day       string             `json:"day"`
+	Date      string             `json:"date"`
+	Intervals []CalendarInterval `json:"intervals"`
+	Gaps      []Gap              `json:"gaps"`
+}
+
+// Calendar returns the rates of the week, Monday to Sunday, that contains week in its timezone.
+// Each day is rendered as wall clock times in the timezone of week, so a rate that crosses
+// midnight in that timezone shows up on both days. The end of a day is rendered as 24:00.
+func (a *API) Calendar(week time.Time) []CalendarDay {
+	tables := a.snapshot()
+	loc := week.Location()
+	// Weekday counts from Sunday, the calendar starts on Monday
+	monday := time.Date(week.Year(), week.Month(), week.Day()-(int(week.Weekday())+6)%7, 0, 0, 0, 0, loc)
+
+	days := make([]CalendarDay, 7)
+	for d := range days {
+		dayStart := time.Date(monday.Year(), monday.Month(), monday.Day()+d, 0, 0, 0, 0, loc)
+		dayEnd := time.Date(monday.Year(), monday.Month(), monday.Day()+d+1, 0, 0, 0, 0, loc)
+		// clock formats tm as HH:MM, or as HH:MM:SS when it falls on a particular second
+		clock := func(tm time.Time) string {
+			if !tm.Before(dayEnd) {
+				return "24:00"
+			}
+			if tm.Second() != 0 {
+				return tm.In(loc).Format("15:04:05")
+			}
+			return tm.In(loc).Format("15:04")
+		}
+
+		spans := spansIn(tables, dayStart, dayEnd)
+		sort.SliceStable(spans, func(i, j int) bool {
+			if !spans[i].start.Equal(spans[j].start) {
+				return spans[i].start.Before(spans[j].start)
+			}
+			return spans[i].end.Before(spans[j].end)
+		})
+
+		day := CalendarDay{
+			Day:       dayStart.Weekday().String(),
+			Date:      dayStart.Format("2006-01-02"),
+			Intervals: []CalendarInterval{},
+			Gaps:      []Gap{},
+		}
+		// covered is the time up to which the day is covered by the intervals so far
+		covered := dayStart
+		for _, s := range spans {
+			day.Intervals = append(day.Intervals, CalendarInterval{
+				Start:    clock(s.start),
+				End:      clock(s.end),
+				Index:    s.rate.index,
+				Override: s.rate.override,
+				Price:    s.rate.price,
+				Pricing:  s.rate.pricing,
+			})
+			if s.start.After(covered) {
+				day.Gaps = append(day.Gaps, Gap{Start: clock(covered), End: clock(s.start)})
+			}
+			if s.end.After(covered) {
+				covered = s.end
+			}
+		}
+		if covered.Before(dayEnd) {
+			day.Gaps = append(day.Gaps, Gap{Start: clock(covered), End: "24:00"})
+		}
+		days[d] = day
+	}
+	return days
+}
@@ -0,0 +1,86 @@
+package rates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendar(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	chicago, err := time.LoadLocation("America/Chicago")
+	assert.Nil(t, err)
+
+	// Any day of the week returns the whole week, from Monday to Sunday
+	days := a.Calendar(time.Date(2020, 4, 1, 0, 0, 0, 0, chicago))
+	assert.Len(t, days, 7)
+	assert.Equal(t, "Monday", days[0].Day)
+	assert.Equal(t, "2020-03-30", days[0].Date)
+	assert.Equal(t, "Sunday", days[6].Day)
+	assert.Equal(t, "2020-04-05", days[6].Date)
+
+	// The gap between the early morning rate and the day rate on Tuesday shows up
+	assert.Equal(t, CalendarDay{
+		Day:  "Tuesday",
+		Date: "2020-03-31",
+		Intervals: []CalendarInterval{
+			{Start: "01:00", End: "07:00", Index: 4, Price: 925},
+			{Start: "09:00", End: "21:00", Index: 0, Price: 1500},
+		},
+		Gaps: []Gap{
+			{Start: "00:00", End: "01:00"},
+			{Start: "07:00", End: "09:00"},
+			{Start: "21:00", End: "24:00"},
+		},
+	}, days[1])
+
+	// In UTC the rates of Sunday evening in Chicago fall on Monday, and the rates of
+	// Monday evening are cut off at midnight
+	days = a.Calendar(time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, CalendarDay{
+		Day:  "Monday",
+		Date: "2020-03-30",
+		Intervals: []CalendarInterval{
+			{Start: "00:00", End: "02:00", Index: 1, Price: 2000},
+			{Start: "06:00", End: "10:00", Index: 3, Price: 1000},
+			{Start: "14:00", End: "24:00", Index: 0, Price: 1500},
+		},
+		Gaps: []Gap{
+			{Start: "02:00", End: "06:00"},
+			{Start: "10:00", End: "14:00"},
+		},
+	}, days[0])
+}
+
+func TestCalendarWithSeconds(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{{Days: "mon", Times: "09:00:30-10:00:00", TZ: "UTC", Price: 1500}},
+	})
+	assert.Nil(t, err)
+
+	days := a.Calendar(time.Date(2020, 4, 6, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, CalendarDay{
+		Day:  "Monday",
+		Date: "2020-04-06",
+		Intervals: []CalendarInterval{
+			{Start: "09:00:30", End: "10:00", Index: 0, Price: 1500},
+		},
+		Gaps: []Gap{
+			{Start: "00:00", End: "09:00:30"},
+			{Start: "10:00", End: "24:00"},
+		},
+	}, days[0])
+}
+
+func TestCalendarWithoutRates(t *testing.T) {
+	a := &API{}
+	days := a.Calendar(time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC))
+	for _, day := range days {
+		assert.Equal(t, []CalendarInterval{}, day.Intervals)
+		assert.Equal(t, []Gap{{Start: "00:00", End: "24:00"}}, day.Gaps)
+	}
+}
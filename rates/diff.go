@@ -0,0 +1,239 @@
+package rates
+
+import (
+	"strings"
+	"time"
+)
+
+// dayOrder lists the abbreviated days from Monday to Sunday, which is the order that diffs are listed in
+var dayOrder = []string{"mon", "tues", "wed", "thurs", "fri", "sat", "sun"}
+
+// RateDiff holds what changed between two sets of rates: the windows of each weekday in Days and
+// the overrides in Overrides
+type RateDiff struct {
+	Days      []DayDiff      `json:"days" xml:"days>day"`
+	Overrides []OverrideDiff `json:"overrides" xml:"overrides>override"`
+}
+
+// DayDiff holds the windows of a weekday that were added, removed or changed between two sets of rates
+// Each window is listed as its rate with only the weekday in Days.
+type DayDiff struct {
+	Day     string       `json:"day" xml:"day"`
+	Added   []RateDetail `json:"added,omitempty" xml:"added>rate,omitempty"`
+	Removed []RateDetail `json:"removed,omitempty" xml:"removed>rate,omitempty"`
+	Changed []Change     `json:"changed,omitempty" xml:"changed>change,omitempty"`
+}
+
+// OverrideDiff holds an override as it was before and after it changed, with the dates that it applies to
+// Before is nil when the override was added and After is nil when it was removed.
+type OverrideDiff struct {
+	Name   string    `json:"name" xml:"name"`
+	Dates  string    `json:"dates" xml:"dates"`
+	Before *Override `json:"before,omitempty" xml:"before,omitempty"`
+	After  *Override `json:"after,omitempty" xml:"after,omitempty"`
+}
+
+// Change holds a window that kept its times and timezone, as it was before and after its price changed
+type Change struct {
+	Before RateDetail `json:"before" xml:"before"`
+	After  RateDetail `json:"after" xml:"after"`
+}
+
+// Diff returns the windows that putting ir would add, remove or change on each weekday and the overrides that
+// it would add, remove or change, without putting it.
+// ir is validated and checked for overlaps like it is by Put, and is compared against the rates that it
+// would replace: the active rates, or the rates in force when it takes effect if it is scheduled.
+func (a *API) Diff(ir IncomingRates) (RateDiff, error) {
+	t, err := newTable(ir)
+	if err != nil {
+		return RateDiff{}, err
+	}
+	now := time.Now()
+	t.effectiveFrom = effectiveFrom(ir, now)
+	return diffRates(replacedRates(a.snapshot(), t, now), t.rates), nil
+}
+
+// replacedRates returns the rates among tables that t replaces when it is put at now,
+// which are the rates in force when t takes effect
+func replacedRates(tables []*rateTable, t *rateTable, now time.Time) IncomingRates {
+	takesEffect := now
+	if !t.effectiveFrom.IsZero() {
+		takesEffect = t.effectiveFrom
+	}
+	if replaced := tableAt(tables, takesEffect); replaced != nil {
+		return copyRates(replaced.rates)
+	}
+	return IncomingRates{Rates: []RateDetail{}}
+}
+
+// diffRates returns the windows that were added, removed or changed from before to after for every
+// weekday that has any, from Monday to Sunday, and the overrides that were added, removed or changed.
+// A window is identified by its times and its timezone.
+func diffRates(before, after IncomingRates) RateDiff {
+	diffs := []DayDiff{}
+	for _, day := range dayOrder {
+		d := DayDiff{Day: dayMap[day]}
+		previous := ratesOn(before, day)
+		next := ratesOn(after, day)
+		// matched marks the windows of next that a window of previous was paired with
+		matched := make([]bool, len(next))
+		for _, p := range previous {
+			j := matchWindow(p, next, matched)
+			if j < 0 {
+				d.Removed = append(d.Removed, p)
+				continue
+			}
+			matched[j] = true
+			if !sameRate(p, next[j]) {
+				d.Changed = append(d.Changed, Change{Before: p, After: next[j]})
+			}
+		}
+		for j, n := range next {
+			if !matched[j] {
+				d.Added = append(d.Added, n)
+			}
+		}
+		if len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Changed) > 0 {
+			diffs = append(diffs, d)
+		}
+	}
+	return RateDiff{Days: diffs, Overrides: diffOverrides(before.Overrides, after.Overrides)}
+}
+
+// diffOverrides returns the overrides that were removed or changed from before, in the order of before,
+// followed by the overrides that were added, in the order of after. An override is identified by its name.
+func diffOverrides(before, after []Override) []OverrideDiff {
+	diffs := []OverrideDiff{}
+	// matched marks the overrides of after that an override of before was paired with
+	matched := make([]bool, len(after))
+	for i := range before {
+		b := &before[i]
+		j := matchOverride(*b, after, matched)
+		if j < 0 {
+			diffs = append(diffs, OverrideDiff{Name: b.Name, Dates: overrideDays(*b), Before: b})
+			continue
+		}
+		matched[j] = true
+		if !sameOverride(*b, after[j]) {
+			a := &after[j]
+			diffs = append(diffs, OverrideDiff{Name: a.Name, Dates: overrideDays(*a), Before: b, After: a})
+		}
+	}
+	for j := range after {
+		if !matched[j] {
+			a := &after[j]
+			diffs = append(diffs, OverrideDiff{Name: a.Name, Dates: overrideDays(*a), After: a})
+		}
+	}
+	return diffs
+}
+
+// matchOverride returns the index of the override among overrides that o is paired with, or -1 if there is none.
+// Overrides that are already matched are skipped, and an unchanged override is preferred like in matchWindow.
+func matchOverride(o Override, overrides []Override, matched []bool) int {
+	found := -1
+	for j, other := range overrides {
+		if matched[j] || o.Name != other.Name {
+			continue
+		}
+		if sameOverride(o, other) {
+			return j
+		}
+		if found < 0 {
+			found = j
+		}
+	}
+	return found
+}
+
+// sameOverride checks if two overrides apply to the same dates in the same timezone with the same rates
+func sameOverride(a, b Override) bool {
+	if a.Date != b.Date || a.From != b.From || a.To != b.To || a.Annual != b.Annual || a.TZ != b.TZ {
+		return false
+	}
+	if len(a.Rates) != len(b.Rates) {
+		return false
+	}
+	for i := range a.Rates {
+		ra := RateDetail{Times: a.Rates[i].Times, TZ: a.TZ, Price: a.Rates[i].Price, Pricing: a.Rates[i].Pricing, Boundary: a.Rates[i].Boundary}
+		rb := RateDetail{Times: b.Rates[i].Times, TZ: b.TZ, Price: b.Rates[i].Price, Pricing: b.Rates[i].Pricing, Boundary: b.Rates[i].Boundary}
+		if !sameWindow(ra, rb) || !sameRate(ra, rb) {
+			return false
+		}
+	}
+	return true
+}
+
+// overrideDays describes the dates that o applies to, such as 2020-12-25, 2020-03-16 to 2020-03-20 or every 12-25
+func overrideDays(o Override) string {
+	switch {
+	case o.Annual != "":
+		return "every " + o.Annual
+	case o.From != "" || o.To != "":
+		return o.From + " to " + o.To
+	}
+	return o.Date
+}
+
+// ratesOn returns the rates of ir that are in force on the abbreviated day, each with only that day in Days
+func ratesOn(ir IncomingRates, day string) []RateDetail {
+	var rates []RateDetail
+	for _, r := range ir.Rates {
+		for _, d := range strings.Split(r.Days, ",") {
+			if d == day {
+				r.Days = day
+				rates = append(rates, r)
+				break
+			}
+		}
+	}
+	return rates
+}
+
+// matchWindow returns the index of the window among rates that r is paired with, or -1 if there is none.
+// Rates that are already matched are skipped. When overlapping rates share a window, an unchanged rate
+// is preferred so that only the rates that actually changed are reported.
+func matchWindow(r RateDetail, rates []RateDetail, matched []bool) int {
+	found := -1
+	for j, other := range rates {
+		if matched[j] || !sameWindow(r, other) {
+			continue
+		}
+		if sameRate(r, other) {
+			return j
+		}
+		if found < 0 {
+			found = j
+		}
+	}
+	return found
+}
+
+// sameWindow checks if two rates cover the same times in the same timezone
+func sameWindow(a, b RateDetail) bool {
+	if a.TZ != b.TZ {
+		return false
+	}
+	aStart, aEnd, aErr := parseTimes(a.Times)
+	bStart, bEnd, bErr := parseTimes(b.Times)
+	// Times that can't be parsed are only the same when they are written the same
+	if aErr != nil || bErr != nil {
+		return a.Times == b.Times
+	}
+	return aStart == bStart && aEnd == bEnd
+}
+
+// sameRate checks if two rates of the same window charge the same, are in force for as long and
+// have the same boundary
+func sameRate(a, b RateDetail) bool {
+	if a.Price != b.Price || boundaryOf(a) != boundaryOf(b) {
+		return false
+	}
+	if (a.Pricing == nil) != (b.Pricing == nil) || (a.Pricing != nil && *a.Pricing != *b.Pricing) {
+		return false
+	}
+	if (a.EffectiveUntil == nil) != (b.EffectiveUntil == nil) {
+		return false
+	}
+	return a.EffectiveUntil == nil || a.EffectiveUntil.Equal(*b.EffectiveUntil)
+}
@@ -0,0 +1,303 @@
+package rates
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffRates(t *testing.T) {
+	until := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+	before := IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon,wed", Times: "0900-1700", TZ: "America/Chicago", Price: 1500},
+			{Days: "mon", Times: "1700-2100", TZ: "America/Chicago", Price: 1000},
+			{Days: "sun", Times: "0000-2400", TZ: "UTC", Pricing: &Pricing{PerIncrement: 200}},
+		},
+	}
+	testCases := []struct {
+		name  string
+		after IncomingRates
+		diff  []DayDiff
+	}{
+		{
+			name:  "Same rates",
+			after: before,
+			diff:  []DayDiff{},
+		},
+		{
+			name: "Same windows listed in another order and with other days grouped together",
+			after: IncomingRates{
+				Rates: []RateDetail{
+					{Days: "sun", Times: "0000-2400", TZ: "UTC", Pricing: &Pricing{PerIncrement: 200}},
+					{Days: "mon", Times: "1700-2100", TZ: "America/Chicago", Price: 1000},
+					{Days: "wed", Times: "0900-1700", TZ: "America/Chicago", Price: 1500},
+					{Days: "mon", Times: "0900-1700", TZ: "America/Chicago", Price: 1500},
+				},
+			},
+			diff: []DayDiff{},
+		},
+		{
+			name: "Price changed on one day only",
+			after: IncomingRates{
+				Rates: []RateDetail{
+					{Days: "mon", Times: "0900-1700", TZ: "America/Chicago", Price: 1500},
+					{Days: "wed", Times: "0900-1700", TZ: "America/Chicago", Price: 1750},
+					{Days: "mon", Times: "1700-2100", TZ: "America/Chicago", Price: 1000},
+					{Days: "sun", Times: "0000-2400", TZ: "UTC", Pricing: &Pricing{PerIncrement: 200}},
+				},
+			},
+			diff: []DayDiff{{
+				Day: "Wednesday",
+				Changed: []Change{{
+					Before: RateDetail{Days: "wed", Times: "0900-1700", TZ: "America/Chicago", Price: 1500},
+					After:  RateDetail{Days: "wed", Times: "0900-1700", TZ: "America/Chicago", Price: 1750},
+				}},
+			}},
+		},
+		{
+			name: "Boundary changed, where the default is the same as a half open boundary",
+			after: IncomingRates{
+				Rates: []RateDetail{
+					{Days: "mon,wed", Times: "0900-1700", TZ: "America/Chicago", Price: 1500, Boundary: BoundaryHalfOpen},
+					{Days: "mon", Times: "1700-2100", TZ: "America/Chicago", Price: 1000, Boundary: BoundaryClosed},
+					{Days: "sun", Times: "0000-2400", TZ: "UTC", Pricing: &Pricing{PerIncrement: 200}},
+				},
+			},
+			diff: []DayDiff{{
+				Day: "Monday",
+				Changed: []Change{{
+					Before: RateDetail{Days: "mon", Times: "1700-2100", TZ: "America/Chicago", Price: 1000},
+					After:  RateDetail{Days: "mon", Times: "1700-2100", TZ: "America/Chicago", Price: 1000, Boundary: BoundaryClosed},
+				}},
+			}},
+		},
+		{
+			name: "Windows added, removed, moved to another timezone and given an expiry or another pricing",
+			after: IncomingRates{
+				Rates: []RateDetail{
+					{Days: "mon,wed", Times: "0900-1700", TZ: "America/Chicago", Price: 1500, EffectiveUntil: &until},
+					{Days: "mon", Times: "1700-2100", TZ: "UTC", Price: 1000},
+					{Days: "sun", Times: "0000-2400", TZ: "UTC", Pricing: &Pricing{PerIncrement: 250}},
+					{Days: "fri", Times: "0600-1800", TZ: "UTC", Price: 800},
+				},
+			},
+			diff: []DayDiff{
+				{
+					Day:     "Monday",
+					Added:   []RateDetail{{Days: "mon", Times: "1700-2100", TZ: "UTC", Price: 1000}},
+					Removed: []RateDetail{{Days: "mon", Times: "1700-2100", TZ: "America/Chicago", Price: 1000}},
+					Changed: []Change{{
+						Before: RateDetail{Days: "mon", Times: "0900-1700", TZ: "America/Chicago", Price: 1500},
+						After:  RateDetail{Days: "mon", Times: "0900-1700", TZ: "America/Chicago", Price: 1500, EffectiveUntil: &until},
+					}},
+				},
+				{
+					Day: "Wednesday",
+					Changed: []Change{{
+						Before: RateDetail{Days: "wed", Times: "0900-1700", TZ: "America/Chicago", Price: 1500},
+						After:  RateDetail{Days: "wed", Times: "0900-1700", TZ: "America/Chicago", Price: 1500, EffectiveUntil: &until},
+					}},
+				},
+				{
+					Day:   "Friday",
+					Added: []RateDetail{{Days: "fri", Times: "0600-1800", TZ: "UTC", Price: 800}},
+				},
+				{
+					Day: "Sunday",
+					Changed: []Change{{
+						Before: RateDetail{Days: "sun", Times: "0000-2400", TZ: "UTC", Pricing: &Pricing{PerIncrement: 200}},
+						After:  RateDetail{Days: "sun", Times: "0000-2400", TZ: "UTC", Pricing: &Pricing{PerIncrement: 250}},
+					}},
+				},
+			},
+		},
+		{
+			name: "Overlapping rates that share a window only report the rate that changed",
+			after: IncomingRates{
+				Rates: []RateDetail{
+					{Days: "mon,wed", Times: "0900-1700", TZ: "America/Chicago", Price: 1500},
+					{Days: "mon", Times: "0900-1700", TZ: "America/Chicago", Price: 1200},
+					{Days: "mon", Times: "1700-2100", TZ: "America/Chicago", Price: 1000},
+					{Days: "sun", Times: "0000-2400", TZ: "UTC", Pricing: &Pricing{PerIncrement: 200}},
+				},
+				AllowOverlap: "lowest",
+			},
+			diff: []DayDiff{{
+				Day:   "Monday",
+				Added: []RateDetail{{Days: "mon", Times: "0900-1700", TZ: "America/Chicago", Price: 1200}},
+			}},
+		},
+		{
+			name:  "Every rate removed",
+			after: IncomingRates{},
+			diff: []DayDiff{
+				{
+					Day: "Monday",
+					Removed: []RateDetail{
+						{Days: "mon", Times: "0900-1700", TZ: "America/Chicago", Price: 1500},
+						{Days: "mon", Times: "1700-2100", TZ: "America/Chicago", Price: 1000},
+					},
+				},
+				{
+					Day:     "Wednesday",
+					Removed: []RateDetail{{Days: "wed", Times: "0900-1700", TZ: "America/Chicago", Price: 1500}},
+				},
+				{
+					Day:     "Sunday",
+					Removed: []RateDetail{{Days: "sun", Times: "0000-2400", TZ: "UTC", Pricing: &Pricing{PerIncrement: 200}}},
+				},
+			},
+		},
+	}
+	for _, tt := range testCases {
+		assert.Equal(t, tt.diff, diffRates(before, tt.after).Days, tt.name)
+	}
+}
+
+func TestDiffOverrides(t *testing.T) {
+	christmas := Override{Name: "Christmas", Annual: "12-25", TZ: "America/Chicago", Rates: []OverrideRate{}}
+	event := Override{Name: "Stadium event", Date: "2020-04-03", TZ: "America/Chicago", Rates: []OverrideRate{{Times: "1600-2359", Price: 4000}}}
+	repriced := event
+	repriced.Rates = []OverrideRate{{Times: "16:00:00-23:59:00", Price: 4500}}
+	before := IncomingRates{Rates: []RateDetail{}, Overrides: []Override{christmas, event}}
+
+	testCases := []struct {
+		name  string
+		after []Override
+		diff  []OverrideDiff
+	}{
+		{
+			name:  "Same overrides",
+			after: []Override{event, christmas},
+			diff:  []OverrideDiff{},
+		},
+		{
+			name:  "Override removed",
+			after: []Override{event},
+			diff:  []OverrideDiff{{Name: "Christmas", Dates: "every 12-25", Before: &christmas}},
+		},
+		{
+			name:  "Override changed",
+			after: []Override{christmas, repriced},
+			diff:  []OverrideDiff{{Name: "Stadium event", Dates: "2020-04-03", Before: &event, After: &repriced}},
+		},
+		{
+			name: "Override added",
+			after: []Override{
+				christmas,
+				event,
+				{Name: "Spring break", From: "2020-03-16", To: "2020-03-20", TZ: "UTC"},
+			},
+			diff: []OverrideDiff{{
+				Name:  "Spring break",
+				Dates: "2020-03-16 to 2020-03-20",
+				After: &Override{Name: "Spring break", From: "2020-03-16", To: "2020-03-20", TZ: "UTC"},
+			}},
+		},
+	}
+	for _, tt := range testCases {
+		after := IncomingRates{Rates: []RateDetail{}, Overrides: tt.after}
+		diff := diffRates(before, after)
+		assert.Empty(t, diff.Days, tt.name)
+		assert.Equal(t, tt.diff, diff.Overrides, tt.name)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	seed := a.List()
+
+	ir := IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon,tues,thurs", Times: "0900-2100", TZ: "America/Chicago", Price: 1750},
+			{Days: "fri,sat,sun", Times: "0900-2100", TZ: "America/Chicago", Price: 2000},
+			{Days: "wed", Times: "0600-1800", TZ: "America/Chicago", Price: 1750},
+			{Days: "mon,wed,sat", Times: "0100-0500", TZ: "America/Chicago", Price: 1000},
+		},
+	}
+	diff, err := a.Diff(ir)
+	assert.Nil(t, err)
+	assert.Equal(t, []DayDiff{
+		{
+			Day: "Monday",
+			Changed: []Change{{
+				Before: RateDetail{ID: "1", Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+				After:  RateDetail{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1750},
+			}},
+		},
+		{
+			Day:     "Tuesday",
+			Removed: []RateDetail{{ID: "5", Days: "tues", Times: "0100-0700", TZ: "America/Chicago", Price: 925}},
+			Changed: []Change{{
+				Before: RateDetail{ID: "1", Days: "tues", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+				After:  RateDetail{Days: "tues", Times: "0900-2100", TZ: "America/Chicago", Price: 1750},
+			}},
+		},
+		{
+			Day: "Thursday",
+			Changed: []Change{{
+				Before: RateDetail{ID: "1", Days: "thurs", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+				After:  RateDetail{Days: "thurs", Times: "0900-2100", TZ: "America/Chicago", Price: 1750},
+			}},
+		},
+		{
+			Day:     "Sunday",
+			Removed: []RateDetail{{ID: "5", Days: "sun", Times: "0100-0700", TZ: "America/Chicago", Price: 925}},
+		},
+	}, diff.Days)
+	assert.Empty(t, diff.Overrides)
+	// assert that the rates aren't put in place
+	assert.Equal(t, seed, a.List())
+	assert.Len(t, a.Versions(), 1)
+
+	// assert that rates that Put would reject are rejected the same way
+	_, err = a.Diff(IncomingRates{Rates: []RateDetail{{Days: "mon", Times: "0900", TZ: "UTC"}}})
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	overlapping := IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon", Times: "0900-1700", TZ: "UTC", Price: 1500},
+			{Days: "mon", Times: "1200-2000", TZ: "UTC", Price: 2000},
+		},
+	}
+	_, err = a.Diff(overlapping)
+	var overlapErr *OverlapError
+	assert.True(t, errors.As(err, &overlapErr))
+	overlapping.AllowOverlap = "lowest"
+	_, err = a.Diff(overlapping)
+	assert.Nil(t, err)
+
+	// assert that a change of only the overrides is listed
+	withHoliday := copyRates(seed)
+	withHoliday.Overrides = []Override{{Name: "Christmas", Annual: "12-25", TZ: "America/Chicago"}}
+	diff, err = a.Diff(withHoliday)
+	assert.Nil(t, err)
+	assert.Empty(t, diff.Days)
+	assert.Equal(t, []OverrideDiff{{
+		Name:  "Christmas",
+		Dates: "every 12-25",
+		After: &withHoliday.Overrides[0],
+	}}, diff.Overrides)
+
+	// assert that scheduled rates are compared against the pending rates that they would replace
+	effectiveFrom := time.Now().Add(24 * time.Hour)
+	pending := IncomingRates{
+		Rates:         []RateDetail{{Days: "sat", Times: "0000-2400", TZ: "UTC", Price: 900}},
+		EffectiveFrom: &effectiveFrom,
+	}
+	assert.Nil(t, a.Put(pending))
+	pending.Rates = []RateDetail{{Days: "sat", Times: "0000-2400", TZ: "UTC", Price: 1200}}
+	diff, err = a.Diff(pending)
+	assert.Nil(t, err)
+	assert.Equal(t, []DayDiff{{
+		Day: "Saturday",
+		Changed: []Change{{
+			Before: RateDetail{ID: "6", Days: "sat", Times: "0000-2400", TZ: "UTC", Price: 900},
+			After:  RateDetail{Days: "sat", Times: "0000-2400", TZ: "UTC", Price: 1200},
+		}},
+	}}, diff.Days)
+}
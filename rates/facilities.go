@@ -0,0 +1,297 @@
+package rates
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"unicode"
+)
+
+var (
+	// ErrFacilityNotFound is returned when there is no facility with the requested id
+	ErrFacilityNotFound = errors.New("facility not found")
+	// ErrFacilityExists is returned when a facility is created with an id that is already taken
+	ErrFacilityExists = errors.New("facility already exists")
+)
+
+// FacilityError is returned when a facility is malformed
+type FacilityError struct {
+	Message string
+}
+
+func (e *FacilityError) Error() string {
+	return e.Message
+}
+
+// Facility is a parking lot or garage with its own rate table
+// TZ is the default timezone of the rates of the facility. It is used for rates that don't have a tz.
+type Facility struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	TZ   string `json:"tz"`
+}
+
+// validate checks that the facility has an id and that its timezone can be loaded
+// The id names the files that the facility is persisted to, so it can only hold letters, digits, - and _.
+func (f Facility) validate() error {
+	if f.ID == "" {
+		return &FacilityError{Message: "facility id is required"}
+	}
+	for _, c := range f.ID {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '-' && c != '_' {
+			return &FacilityError{Message: fmt.Sprintf("facility id can only contain letters, digits, - and _: %q", f.ID)}
+		}
+	}
+	if f.TZ != "" {
+		if _, err := time.LoadLocation(f.TZ); err != nil {
+			return &FacilityError{Message: fmt.Sprintf("unknown timezone: %q", f.TZ)}
+		}
+	}
+	return nil
+}
+
+// facilityRates is the rate service of a single facility
+// It fills in the timezone of the facility for rates that are put without one
+type facilityRates struct {
+	*API
+	tz string
+}
+
+// Put stores the new rates of the facility
+func (f *facilityRates) Put(ir IncomingRates) error {
+	return f.API.Put(f.withTZ(ir))
+}
+
+// Diff compares the new rates of the facility against its rates without storing them
+func (f *facilityRates) Diff(ir IncomingRates) (RateDiff, error) {
+	return f.API.Diff(f.withTZ(ir))
+}
+
+// Patch changes the rates of the facility
+func (f *facilityRates) Patch(p RatePatch) (IncomingRates, error) {
+	p.Add = f.withTZ(IncomingRates{Rates: p.Add}).Rates
+	p.Update = f.withTZ(IncomingRates{Rates: p.Update}).Rates
+	p.Overrides = f.withTZ(IncomingRates{Overrides: p.Overrides}).Overrides
+	return f.API.Patch(p)
+}
+
+// withTZ returns a copy of ir where the rates and overrides without a timezone have the timezone of the facility
+func (f *facilityRates) withTZ(ir IncomingRates) IncomingRates {
+	if f.tz == "" {
+		return ir
+	}
+	rates := copyRates(ir)
+	for i := range rates.Rates {
+		if rates.Rates[i].TZ == "" {
+			rates.Rates[i].TZ = f.tz
+		}
+	}
+	for i := range rates.Overrides {
+		if rates.Overrides[i].TZ == "" {
+			rates.Overrides[i].TZ = f.tz
+		}
+	}
+	return rates
+}
+
+// Facilities implements the interface to manage facilities, each with a separate rate table
+type Facilities struct {
+	facilities map[string]*facilityRates
+	details    map[string]Facility
+	store      FacilityStore
+	rates      func(id string) Options
+	mu         sync.Mutex
+}
+
+// FacilityOptions configures how the facilities and their rates are persisted. The zero value keeps
+// the facilities and their rates in memory only.
+type FacilityOptions struct {
+	// Store saves the facilities whenever they change. The facilities are loaded from it on startup.
+	Store FacilityStore
+	// Rates returns the options of the rates of the facility with the given id, such as the store that they
+	// are saved to, which has to be separate for every facility. The stored rates of a facility are loaded
+	// along with it.
+	Rates func(id string) Options
+}
+
+// NewFacilities returns a new instance of Facilities without any facilities
+func NewFacilities() *Facilities {
+	return &Facilities{
+		facilities: make(map[string]*facilityRates),
+		details:    make(map[string]Facility),
+		rates:      func(string) Options { return Options{} },
+	}
+}
+
+// NewFacilitiesWithOptions returns a new instance of Facilities with the facilities of the store of opts,
+// each with its stored rates
+func NewFacilitiesWithOptions(opts FacilityOptions) (*Facilities, error) {
+	fs := NewFacilities()
+	fs.store = opts.Store
+	if opts.Rates != nil {
+		fs.rates = opts.Rates
+	}
+	if fs.store == nil {
+		return fs, nil
+	}
+	facilities, err := fs.store.LoadFacilities()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range facilities {
+		a, err := newFacilityAPI(f.ID, fs.rates(f.ID))
+		if err != nil {
+			return nil, fmt.Errorf("facility %s: %v", f.ID, err)
+		}
+		fs.details[f.ID] = f
+		fs.facilities[f.ID] = &facilityRates{API: a, tz: f.TZ}
+	}
+	return fs, nil
+}
+
+// newFacilityAPI returns the API of the rates of the facility with the given id with the store, audit log
+// and version log of opts. It loads the rates of the store, and a facility without stored rates starts out
+// without any rates. The rates that it accepts are audited with the id of the facility.
+func newFacilityAPI(id string, opts Options) (*API, error) {
+	a := &API{facility: id}
+	loaded, err := a.load(opts.Store)
+	if err != nil {
+		return nil, err
+	}
+	if !loaded {
+		if err := a.Put(IncomingRates{}); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.open(opts); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// CreateFacility adds a new facility with an empty rate table
+// When the rates of facilities are stored, a facility that was deleted gets back its stored rates
+// when it is created again.
+func (fs *Facilities) CreateFacility(f Facility) (Facility, error) {
+	if err := f.validate(); err != nil {
+		return Facility{}, err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, ok := fs.details[f.ID]; ok {
+		return Facility{}, ErrFacilityExists
+	}
+	a, err := newFacilityAPI(f.ID, fs.rates(f.ID))
+	if err != nil {
+		return Facility{}, err
+	}
+	if err := fs.save(f.ID, &f); err != nil {
+		return Facility{}, err
+	}
+	fs.details[f.ID] = f
+	fs.facilities[f.ID] = &facilityRates{API: a, tz: f.TZ}
+	return f, nil
+}
+
+// save saves the facilities to the store with the facility with the given id replaced by f,
+// or removed when f is nil. The caller must hold fs.mu.
+func (fs *Facilities) save(id string, f *Facility) error {
+	if fs.store == nil {
+		return nil
+	}
+	facilities := make([]Facility, 0, len(fs.details)+1)
+	for _, existing := range fs.list() {
+		if existing.ID != id {
+			facilities = append(facilities, existing)
+		}
+	}
+	if f != nil {
+		facilities = append(facilities, *f)
+		sort.Slice(facilities, func(i, j int) bool {
+			return facilities[i].ID < facilities[j].ID
+		})
+	}
+	return fs.store.SaveFacilities(facilities)
+}
+
+// Facilities returns every facility ordered by id
+func (fs *Facilities) Facilities() []Facility {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.list()
+}
+
+// list returns every facility ordered by id. The caller must hold fs.mu.
+func (fs *Facilities) list() []Facility {
+	facilities := make([]Facility, 0, len(fs.details))
+	for _, f := range fs.details {
+		facilities = append(facilities, f)
+	}
+	sort.Slice(facilities, func(i, j int) bool {
+		return facilities[i].ID < facilities[j].ID
+	})
+	return facilities
+}
+
+// Facility returns the facility with the given id
+func (fs *Facilities) Facility(id string) (Facility, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	f, ok := fs.details[id]
+	if !ok {
+		return Facility{}, ErrFacilityNotFound
+	}
+	return f, nil
+}
+
+// UpdateFacility updates the name and timezone of an existing facility. Its rates are kept as they are.
+func (fs *Facilities) UpdateFacility(f Facility) (Facility, error) {
+	if err := f.validate(); err != nil {
+		return Facility{}, err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	existing, ok := fs.facilities[f.ID]
+	if !ok {
+		return Facility{}, ErrFacilityNotFound
+	}
+	if err := fs.save(f.ID, &f); err != nil {
+		return Facility{}, err
+	}
+	fs.details[f.ID] = f
+	fs.facilities[f.ID] = &facilityRates{API: existing.API, tz: f.TZ}
+	return f, nil
+}
+
+// DeleteFacility removes the facility with the given id along with its rates
+func (fs *Facilities) DeleteFacility(id string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, ok := fs.details[id]; !ok {
+		return ErrFacilityNotFound
+	}
+	if err := fs.save(id, nil); err != nil {
+		return err
+	}
+	delete(fs.details, id)
+	delete(fs.facilities, id)
+	return nil
+}
+
+// Rates returns the rate service of the facility with the given id
+func (fs *Facilities) Rates(id string) (Service, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	s, ok := fs.facilities[id]
+	if !ok {
+		return nil, ErrFacilityNotFound
+	}
+	return s, nil
+}
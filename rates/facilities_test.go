@@ -0,0 +1,166 @@
+package rates
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFacilities(t *testing.T) {
+	fs := NewFacilities()
+
+	_, err := fs.CreateFacility(Facility{Name: "No id"})
+	assert.EqualError(t, err, "facility id is required")
+	_, err = fs.CreateFacility(Facility{ID: "mars", TZ: "Mars/Olympus_Mons"})
+	assert.EqualError(t, err, `unknown timezone: "Mars/Olympus_Mons"`)
+	_, err = fs.CreateFacility(Facility{ID: "../downtown"})
+	assert.EqualError(t, err, `facility id can only contain letters, digits, - and _: "../downtown"`)
+
+	downtown, err := fs.CreateFacility(Facility{ID: "downtown", Name: "Downtown Garage", TZ: "America/Chicago"})
+	assert.Nil(t, err)
+	_, err = fs.CreateFacility(Facility{ID: "airport", Name: "Airport Lot", TZ: "America/Los_Angeles"})
+	assert.Nil(t, err)
+	_, err = fs.CreateFacility(Facility{ID: "downtown"})
+	assert.Equal(t, ErrFacilityExists, err)
+
+	// Facilities are listed by id
+	assert.Equal(t, []Facility{
+		{ID: "airport", Name: "Airport Lot", TZ: "America/Los_Angeles"},
+		downtown,
+	}, fs.Facilities())
+
+	f, err := fs.Facility("downtown")
+	assert.Nil(t, err)
+	assert.Equal(t, downtown, f)
+	_, err = fs.Facility("uptown")
+	assert.Equal(t, ErrFacilityNotFound, err)
+
+	_, err = fs.UpdateFacility(Facility{ID: "uptown", Name: "Uptown Garage"})
+	assert.Equal(t, ErrFacilityNotFound, err)
+	f, err = fs.UpdateFacility(Facility{ID: "downtown", Name: "Downtown Parking", TZ: "America/Chicago"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Downtown Parking", f.Name)
+
+	assert.Nil(t, fs.DeleteFacility("airport"))
+	assert.Equal(t, ErrFacilityNotFound, fs.DeleteFacility("airport"))
+	_, err = fs.Rates("airport")
+	assert.Equal(t, ErrFacilityNotFound, err)
+	assert.Equal(t, []Facility{f}, fs.Facilities())
+}
+
+func TestFacilityRates(t *testing.T) {
+	fs := NewFacilities()
+	_, err := fs.CreateFacility(Facility{ID: "downtown", TZ: "America/Chicago"})
+	assert.Nil(t, err)
+	_, err = fs.CreateFacility(Facility{ID: "airport", TZ: "America/Los_Angeles"})
+	assert.Nil(t, err)
+
+	downtown, err := fs.Rates("downtown")
+	assert.Nil(t, err)
+	airport, err := fs.Rates("airport")
+	assert.Nil(t, err)
+
+	// A new facility has no rates
+	assert.Equal(t, []RateDetail{}, downtown.List().Rates)
+
+	// Rates without a tz take the timezone of the facility, including when they are only compared
+	diff, err := downtown.Diff(IncomingRates{Rates: []RateDetail{{Days: "mon", Times: "0900-1700", Price: 1500}}})
+	assert.Nil(t, err)
+	assert.Equal(t, []DayDiff{{
+		Day:   "Monday",
+		Added: []RateDetail{{Days: "mon", Times: "0900-1700", TZ: "America/Chicago", Price: 1500}},
+	}}, diff.Days)
+	err = downtown.Put(IncomingRates{Rates: []RateDetail{{Days: "mon", Times: "0900-1700", Price: 1500}}})
+	assert.Nil(t, err)
+	err = airport.Put(IncomingRates{Rates: []RateDetail{{Days: "mon", Times: "0900-1700", Price: 2500}}})
+	assert.Nil(t, err)
+	assert.Equal(t, []RateDetail{{ID: "1", Days: "mon", Times: "0900-1700", TZ: "America/Chicago", Price: 1500}}, downtown.List().Rates)
+	ir, err := downtown.Patch(RatePatch{Add: []RateDetail{{Days: "tues", Times: "0900-1700", Price: 1500}}})
+	assert.Nil(t, err)
+	assert.Equal(t, RateDetail{ID: "2", Days: "tues", Times: "0900-1700", TZ: "America/Chicago", Price: 1500}, ir.Rates[1])
+	ir, err = downtown.Patch(RatePatch{Overrides: []Override{{Name: "Christmas", Annual: "12-25"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "America/Chicago", ir.Overrides[0].TZ)
+
+	// 2015-07-06 is a Monday. 10am in Chicago is 8am in Los Angeles, when only downtown is open.
+	p := ParkingTimesRequest{
+		StartTime: time.Date(2015, 7, 6, 10, 0, 0, 0, time.FixedZone("CDT", -5*3600)),
+		EndTime:   time.Date(2015, 7, 6, 11, 0, 0, 0, time.FixedZone("CDT", -5*3600)),
+	}
+	rate, err := downtown.Get(p)
+	assert.Nil(t, err)
+	assert.Equal(t, 1500, rate)
+	_, err = airport.Get(p)
+	assert.NotNil(t, err)
+
+	// The rates of a facility are kept when it is updated
+	_, err = fs.UpdateFacility(Facility{ID: "downtown", Name: "Downtown Garage", TZ: "America/Chicago"})
+	assert.Nil(t, err)
+	downtown, err = fs.Rates("downtown")
+	assert.Nil(t, err)
+	rate, err = downtown.Get(p)
+	assert.Nil(t, err)
+	assert.Equal(t, 1500, rate)
+}
+
+func TestFacilitiesWithStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	opts := FacilityOptions{
+		Store: NewFileFacilityStore(filepath.Join(dir, "facilities.json")),
+		Rates: func(id string) Options {
+			return Options{
+				Store:    NewFileStore(filepath.Join(dir, id+".json")),
+				Versions: NewFileVersionLog(filepath.Join(dir, id+".versions.jsonl")),
+			}
+		},
+	}
+	fs, err := NewFacilitiesWithOptions(opts)
+	assert.Nil(t, err)
+	assert.Empty(t, fs.Facilities())
+
+	downtown, err := fs.CreateFacility(Facility{ID: "downtown", Name: "Downtown Garage", TZ: "America/Chicago"})
+	assert.Nil(t, err)
+	_, err = fs.CreateFacility(Facility{ID: "airport", Name: "Airport Lot", TZ: "America/Los_Angeles"})
+	assert.Nil(t, err)
+	s, err := fs.Rates("downtown")
+	assert.Nil(t, err)
+	assert.Nil(t, s.Put(IncomingRates{Rates: []RateDetail{{Days: "mon", Times: "0900-1700", Price: 1500}}}))
+	assert.Nil(t, fs.DeleteFacility("airport"))
+
+	// assert that the facilities, their rates and their versions are loaded on startup
+	fs, err = NewFacilitiesWithOptions(opts)
+	assert.Nil(t, err)
+	assert.Equal(t, []Facility{downtown}, fs.Facilities())
+	s, err = fs.Rates("downtown")
+	assert.Nil(t, err)
+	assert.Equal(t, []RateDetail{{ID: "1", Days: "mon", Times: "0900-1700", TZ: "America/Chicago", Price: 1500}}, s.List().Rates)
+	assert.Len(t, s.Versions(), 2)
+}
+
+func TestFacilitiesWhenStoreFails(t *testing.T) {
+	fs, err := NewFacilitiesWithOptions(FacilityOptions{Store: &failingFacilityStore{}})
+	assert.Nil(t, err)
+
+	_, err = fs.CreateFacility(Facility{ID: "downtown"})
+	assert.Equal(t, errors.New("disk full"), err)
+	// assert that the facility that couldn't be saved was not created
+	assert.Empty(t, fs.Facilities())
+}
+
+type failingFacilityStore struct{}
+
+func (f *failingFacilityStore) LoadFacilities() ([]Facility, error) {
+	return []Facility{}, nil
+}
+
+func (f *failingFacilityStore) SaveFacilities(facilities []Facility) error {
+	return errors.New("disk full")
+}
@@ -0,0 +1,368 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/theblueskies/spothro/rates/ratespb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GRPCServer implements the gRPC Rates service on top of a Service
+// Like the router, it only depends on the interface and not on its implementation
+type GRPCServer struct {
+	s Service
+}
+
+// grpcRoles holds the role that is required to call each method of the Rates service
+// Like the router, quote clients can only get rates while pricing admins can call every method
+var grpcRoles = map[string]string{
+	"/spothro.rates.Rates/GetRate":       RoleQuote,
+	"/spothro.rates.Rates/PutRates":      RoleAdmin,
+	"/spothro.rates.Rates/ListRates":     RoleAdmin,
+	"/spothro.rates.Rates/ListOverrides": RoleAdmin,
+}
+
+// NewGRPCServer returns a gRPC server with the Rates service registered
+func NewGRPCServer(s Service) *grpc.Server {
+	return NewGRPCServerWithAuth(s, nil)
+}
+
+// NewGRPCServerWithAuth returns a gRPC server with the Rates service registered whose methods require authentication
+// The credentials are sent in the x-api-key or authorization metadata, the same way as the HTTP headers.
+// A nil auth leaves every method open.
+func NewGRPCServerWithAuth(s Service, auth *Auth) *grpc.Server {
+	var opts []grpc.ServerOption
+	if auth != nil {
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				caller, err := auth.authorizeGRPC(ctx, info.FullMethod)
+				if err != nil {
+					return nil, err
+				}
+				return handler(context.WithValue(ctx, grpcCallerKey{}, caller), req)
+			}),
+			grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if _, err := auth.authorizeGRPC(ss.Context(), info.FullMethod); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}),
+		)
+	}
+	srv := grpc.NewServer(opts...)
+	ratespb.RegisterRatesServer(srv, &GRPCServer{s: s})
+	return srv
+}
+
+// grpcCallerKey is the key that the authenticated caller is stored under in the context of a gRPC call
+type grpcCallerKey struct{}
+
+// authorizeGRPC authenticates the caller of a gRPC method from the metadata of the call and checks its role
+func (a *Auth) authorizeGRPC(ctx context.Context, method string) (Caller, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	caller, err := a.authenticate(first("x-api-key"), first("authorization"))
+	if err != nil {
+		return Caller{}, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if !caller.allowed([]string{grpcRoles[method]}) {
+		return Caller{}, status.Errorf(codes.PermissionDenied, "role %s is not allowed to call %s", caller.Role, method)
+	}
+	return caller, nil
+}
+
+// callerFromContext returns the caller that was authenticated for a gRPC call
+// It returns false when the call wasn't authenticated
+func callerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(grpcCallerKey{}).(Caller)
+	return caller, ok
+}
+
+// GetRate is a wrapper around the Service Quote function
+// A rate that is unavailable is returned as a NotFound error
+func (g *GRPCServer) GetRate(ctx context.Context, req *ratespb.GetRateRequest) (*ratespb.RateResponse, error) {
+	if req.StartTime == nil || req.EndTime == nil {
+		return nil, status.Error(codes.InvalidArgument, "start_time and end_time are required")
+	}
+	loc := time.UTC
+	if req.Tz != "" {
+		var err error
+		loc, err = time.LoadLocation(req.Tz)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown timezone: %s", req.Tz)
+		}
+	}
+	if !validStitch(req.Stitch) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown stitch mode: %s", req.Stitch)
+	}
+	q, err := g.s.Quote(ParkingTimesRequest{
+		StartTime: req.StartTime.AsTime().In(loc),
+		EndTime:   req.EndTime.AsTime().In(loc),
+		MultiDay:  req.MultiDay,
+		Stitch:    req.Stitch,
+	})
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return toProtoRateResponse(RateResponse{
+		Status:   "success",
+		Message:  "success retrieving rate",
+		Rate:     q.Rate,
+		Segments: q.Segments,
+		Windows:  q.Windows,
+	}), nil
+}
+
+// PutRates is a wrapper around the Service Put function
+// Rates that are malformed or overlap are rejected with an InvalidArgument error, which carries a
+// PutResponse listing every invalid field or every conflicting pair of rates as its details
+func (g *GRPCServer) PutRates(ctx context.Context, req *ratespb.PutRatesRequest) (*ratespb.PutResponse, error) {
+	if !validOverlapPolicy(req.AllowOverlap) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown overlap policy: %s", req.AllowOverlap)
+	}
+	ir := fromProtoRates(req)
+	// The authenticated caller is recorded in the audit log
+	ir.Caller, _ = callerFromContext(ctx)
+	err := g.s.Put(ir)
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return nil, statusWithDetails(codes.InvalidArgument, PutResponse{
+			Status:  "error",
+			Message: validationErr.Error(),
+			Errors:  validationErr.Errors,
+		})
+	}
+	var overlapErr *OverlapError
+	if errors.As(err, &overlapErr) {
+		return nil, statusWithDetails(codes.InvalidArgument, PutResponse{
+			Status:    "error",
+			Message:   overlapErr.Error(),
+			Conflicts: overlapErr.Conflicts,
+		})
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return toProtoPutResponse(PutResponse{
+		Status:  "success",
+		Message: "Successfully updated rates",
+	}), nil
+}
+
+// ListRates is a wrapper around the Service List function
+// It streams the active rates one at a time in the order that they were put
+func (g *GRPCServer) ListRates(req *ratespb.ListRatesRequest, stream ratespb.Rates_ListRatesServer) error {
+	for _, r := range g.s.List().Rates {
+		if err := stream.Send(toProtoRateDetail(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListOverrides is a wrapper around the Service List function
+// It streams the overrides of the active rates one at a time in the order that they were put
+func (g *GRPCServer) ListOverrides(req *ratespb.ListRatesRequest, stream ratespb.Rates_ListOverridesServer) error {
+	for _, o := range g.s.List().Overrides {
+		if err := stream.Send(toProtoOverride(o)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statusWithDetails returns an error with the code and the message of the response, carrying the response as its details
+func statusWithDetails(code codes.Code, resp PutResponse) error {
+	st, err := status.New(code, resp.Message).WithDetails(toProtoPutResponse(resp))
+	if err != nil {
+		return status.Error(codes.Internal, fmt.Sprintf("%s: %s", resp.Message, err))
+	}
+	return st.Err()
+}
+
+// toProtoTime converts a time to a protobuf timestamp. A nil time is converted to a nil timestamp.
+func toProtoTime(tm *time.Time) *timestamppb.Timestamp {
+	if tm == nil {
+		return nil
+	}
+	return timestamppb.New(*tm)
+}
+
+// fromProtoTime converts a protobuf timestamp to a time. A nil timestamp is converted to a nil time.
+func fromProtoTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	tm := ts.AsTime()
+	return &tm
+}
+
+// toProtoRateResponse converts a RateResponse to its protobuf message
+func toProtoRateResponse(resp RateResponse) *ratespb.RateResponse {
+	pb := &ratespb.RateResponse{
+		Status:  resp.Status,
+		Message: resp.Message,
+		Rate:    int64(resp.Rate),
+	}
+	for _, s := range resp.Segments {
+		pb.Segments = append(pb.Segments, &ratespb.Segment{
+			StartTime: timestamppb.New(s.StartTime),
+			EndTime:   timestamppb.New(s.EndTime),
+			Rate:      int64(s.Rate),
+		})
+	}
+	for _, w := range resp.Windows {
+		pb.Windows = append(pb.Windows, &ratespb.Window{
+			Index:     int64(w.Index),
+			Day:       w.Day,
+			Times:     w.Times,
+			Tz:        w.TZ,
+			StartTime: timestamppb.New(w.StartTime),
+			EndTime:   timestamppb.New(w.EndTime),
+			Rate:      int64(w.Rate),
+			Override:  w.Override,
+		})
+	}
+	return pb
+}
+
+// toProtoPutResponse converts a PutResponse to its protobuf message
+func toProtoPutResponse(resp PutResponse) *ratespb.PutResponse {
+	pb := &ratespb.PutResponse{
+		Status:  resp.Status,
+		Message: resp.Message,
+	}
+	for _, c := range resp.Conflicts {
+		pb.Conflicts = append(pb.Conflicts, &ratespb.Conflict{
+			First:  int64(c.First),
+			Second: int64(c.Second),
+			Days:   c.Days,
+		})
+	}
+	for _, e := range resp.Errors {
+		pb.Errors = append(pb.Errors, &ratespb.FieldError{
+			Index:   int64(e.Index),
+			Field:   e.Field,
+			Message: e.Message,
+		})
+	}
+	return pb
+}
+
+// toProtoRateDetail converts a RateDetail to its protobuf message
+func toProtoRateDetail(r RateDetail) *ratespb.RateDetail {
+	pb := &ratespb.RateDetail{
+		Id:             r.ID,
+		Days:           r.Days,
+		Times:          r.Times,
+		Tz:             r.TZ,
+		Price:          int64(r.Price),
+		EffectiveUntil: toProtoTime(r.EffectiveUntil),
+		Boundary:       r.Boundary,
+	}
+	pb.Pricing = toProtoPricing(r.Pricing)
+	return pb
+}
+
+// toProtoOverride converts an Override to its protobuf message
+func toProtoOverride(o Override) *ratespb.Override {
+	pb := &ratespb.Override{
+		Name:   o.Name,
+		Date:   o.Date,
+		From:   o.From,
+		To:     o.To,
+		Annual: o.Annual,
+		Tz:     o.TZ,
+	}
+	for _, r := range o.Rates {
+		pb.Rates = append(pb.Rates, &ratespb.OverrideRate{
+			Times:    r.Times,
+			Price:    int64(r.Price),
+			Pricing:  toProtoPricing(r.Pricing),
+			Boundary: r.Boundary,
+		})
+	}
+	return pb
+}
+
+// toProtoPricing converts a Pricing to its protobuf message. A flat price is converted to a nil message.
+func toProtoPricing(p *Pricing) *ratespb.Pricing {
+	if p == nil {
+		return nil
+	}
+	return &ratespb.Pricing{
+		IncrementMinutes: int64(p.IncrementMinutes),
+		PerIncrement:     int64(p.PerIncrement),
+		FirstHour:        int64(p.FirstHour),
+		DailyMax:         int64(p.DailyMax),
+	}
+}
+
+// fromProtoRates converts the protobuf message of new rates to IncomingRates
+func fromProtoRates(req *ratespb.PutRatesRequest) IncomingRates {
+	ir := IncomingRates{
+		Rates:         make([]RateDetail, len(req.Rates)),
+		AllowOverlap:  req.AllowOverlap,
+		Author:        req.Author,
+		Comment:       req.Comment,
+		EffectiveFrom: fromProtoTime(req.EffectiveFrom),
+	}
+	for _, o := range req.Overrides {
+		override := Override{
+			Name:   o.Name,
+			Date:   o.Date,
+			From:   o.From,
+			To:     o.To,
+			Annual: o.Annual,
+			TZ:     o.Tz,
+			Rates:  []OverrideRate{},
+		}
+		for _, r := range o.Rates {
+			override.Rates = append(override.Rates, OverrideRate{
+				Times:    r.Times,
+				Price:    int(r.Price),
+				Pricing:  fromProtoPricing(r.Pricing),
+				Boundary: r.Boundary,
+			})
+		}
+		ir.Overrides = append(ir.Overrides, override)
+	}
+	for i, r := range req.Rates {
+		ir.Rates[i] = RateDetail{
+			ID:             r.Id,
+			Days:           r.Days,
+			Times:          r.Times,
+			TZ:             r.Tz,
+			Price:          int(r.Price),
+			EffectiveUntil: fromProtoTime(r.EffectiveUntil),
+			Boundary:       r.Boundary,
+			Pricing:        fromProtoPricing(r.Pricing),
+		}
+	}
+	return ir
+}
+
+// fromProtoPricing converts the protobuf message of a pricing to a Pricing. A nil message is a flat price.
+func fromProtoPricing(pb *ratespb.Pricing) *Pricing {
+	if pb == nil {
+		return nil
+	}
+	return &Pricing{
+		IncrementMinutes: int(pb.IncrementMinutes),
+		PerIncrement:     int(pb.PerIncrement),
+		FirstHour:        int(pb.FirstHour),
+		DailyMax:         int(pb.DailyMax),
+	}
+}
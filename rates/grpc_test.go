@@ -0,0 +1,290 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/theblueskies/spothro/rates/ratespb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newGRPCClient serves the Rates service on top of s on an in-process listener and returns a client for it
+// The returned function stops the server and closes the client
+func newGRPCClient(t *testing.T, s Service) (ratespb.RatesClient, func()) {
+	return newGRPCClientWithServer(t, NewGRPCServer(s))
+}
+
+// newGRPCClientWithServer serves srv on an in-process listener and returns a client for it
+func newGRPCClientWithServer(t *testing.T, srv *grpc.Server) (ratespb.RatesClient, func()) {
+	lis := bufconn.Listen(1024 * 1024)
+	go srv.Serve(lis)
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(dialer), grpc.WithInsecure())
+	assert.Nil(t, err)
+	return ratespb.NewRatesClient(conn), func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func TestGRPCGetRate(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	client, stop := newGRPCClient(t, a)
+	defer stop()
+
+	testCases := []struct {
+		name string
+		req  *ratespb.GetRateRequest
+		resp *ratespb.RateResponse
+		code codes.Code
+	}{
+		{
+			name: "Rate returned successfully",
+			req: &ratespb.GetRateRequest{
+				StartTime: timestamppb.New(time.Date(2020, 4, 3, 14, 30, 0, 0, time.UTC)),
+				EndTime:   timestamppb.New(time.Date(2020, 4, 3, 19, 30, 0, 0, time.UTC)),
+			},
+			resp: &ratespb.RateResponse{Status: "success", Message: "success retrieving rate", Rate: 2000},
+			code: codes.OK,
+		},
+		{
+			name: "Time range is in the timezone of the request",
+			req: &ratespb.GetRateRequest{
+				StartTime: timestamppb.New(time.Date(2020, 4, 4, 14, 0, 0, 0, time.UTC)),
+				EndTime:   timestamppb.New(time.Date(2020, 4, 5, 2, 0, 0, 0, time.UTC)),
+				Tz:        "America/Chicago",
+			},
+			resp: &ratespb.RateResponse{Status: "success", Message: "success retrieving rate", Rate: 2000},
+			code: codes.OK,
+		},
+		{
+			name: "Time range spans midnight in UTC",
+			req: &ratespb.GetRateRequest{
+				StartTime: timestamppb.New(time.Date(2020, 4, 4, 14, 0, 0, 0, time.UTC)),
+				EndTime:   timestamppb.New(time.Date(2020, 4, 5, 2, 0, 0, 0, time.UTC)),
+			},
+			code: codes.NotFound,
+		},
+		{
+			name: "Multi day rate",
+			req: &ratespb.GetRateRequest{
+				StartTime: timestamppb.New(time.Date(2020, 4, 4, 14, 0, 0, 0, time.UTC)),
+				EndTime:   timestamppb.New(time.Date(2020, 4, 5, 2, 0, 0, 0, time.UTC)),
+				MultiDay:  true,
+				Tz:        "America/Chicago",
+			},
+			resp: &ratespb.RateResponse{
+				Status:  "success",
+				Message: "success retrieving rate",
+				Rate:    2000,
+				Segments: []*ratespb.Segment{
+					{
+						StartTime: timestamppb.New(time.Date(2020, 4, 4, 14, 0, 0, 0, time.UTC)),
+						EndTime:   timestamppb.New(time.Date(2020, 4, 5, 2, 0, 0, 0, time.UTC)),
+						Rate:      2000,
+					},
+				},
+			},
+			code: codes.OK,
+		},
+		{
+			name: "Unknown timezone",
+			req: &ratespb.GetRateRequest{
+				StartTime: timestamppb.New(time.Date(2020, 4, 4, 14, 0, 0, 0, time.UTC)),
+				EndTime:   timestamppb.New(time.Date(2020, 4, 5, 2, 0, 0, 0, time.UTC)),
+				Tz:        "Mars/Olympus_Mons",
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "Rate unavailable",
+			req: &ratespb.GetRateRequest{
+				StartTime: timestamppb.New(time.Date(2020, 4, 4, 7, 0, 0, 0, time.UTC)),
+				EndTime:   timestamppb.New(time.Date(2020, 4, 4, 20, 0, 0, 0, time.UTC)),
+			},
+			code: codes.NotFound,
+		},
+		{
+			name: "Missing end time",
+			req: &ratespb.GetRateRequest{
+				StartTime: timestamppb.New(time.Date(2020, 4, 3, 14, 30, 0, 0, time.UTC)),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "Unknown stitch mode",
+			req: &ratespb.GetRateRequest{
+				StartTime: timestamppb.New(time.Date(2020, 4, 3, 14, 30, 0, 0, time.UTC)),
+				EndTime:   timestamppb.New(time.Date(2020, 4, 3, 19, 30, 0, 0, time.UTC)),
+				Stitch:    "min",
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range testCases {
+		resp, err := client.GetRate(context.Background(), tt.req)
+		assert.Equal(t, tt.code, status.Code(err), tt.name)
+		if tt.resp == nil || resp == nil {
+			assert.Nil(t, tt.resp, tt.name)
+			assert.Nil(t, resp, tt.name)
+			continue
+		}
+		assert.Equal(t, tt.resp.Status, resp.Status, tt.name)
+		assert.Equal(t, tt.resp.Message, resp.Message, tt.name)
+		assert.Equal(t, tt.resp.Rate, resp.Rate, tt.name)
+		assert.Equal(t, len(tt.resp.Segments), len(resp.Segments), tt.name)
+		for i := range resp.Segments {
+			assert.True(t, tt.resp.Segments[i].StartTime.AsTime().Equal(resp.Segments[i].StartTime.AsTime()), tt.name)
+			assert.True(t, tt.resp.Segments[i].EndTime.AsTime().Equal(resp.Segments[i].EndTime.AsTime()), tt.name)
+			assert.Equal(t, tt.resp.Segments[i].Rate, resp.Segments[i].Rate, tt.name)
+		}
+	}
+}
+
+func TestGRPCPutRates(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	client, stop := newGRPCClient(t, a)
+	defer stop()
+
+	// Malformed rates are rejected with every invalid field in the details
+	_, err = client.PutRates(context.Background(), &ratespb.PutRatesRequest{
+		Rates: []*ratespb.RateDetail{{Days: "mon", Times: "0900", Tz: "America/Chicago", Price: 1500}},
+	})
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "invalid rates: 1 field errors", st.Message())
+	assert.Len(t, st.Details(), 1)
+	details := st.Details()[0].(*ratespb.PutResponse)
+	assert.Equal(t, "times", details.Errors[0].Field)
+
+	// Overlapping rates are rejected with every conflicting pair of rates in the details
+	overlapping := []*ratespb.RateDetail{
+		{Days: "mon", Times: "0900-1700", Tz: "America/Chicago", Price: 1500},
+		{Days: "mon", Times: "1200-2000", Tz: "America/Chicago", Price: 2000},
+	}
+	_, err = client.PutRates(context.Background(), &ratespb.PutRatesRequest{Rates: overlapping})
+	st = status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	details = st.Details()[0].(*ratespb.PutResponse)
+	assert.Equal(t, int64(0), details.Conflicts[0].First)
+	assert.Equal(t, int64(1), details.Conflicts[0].Second)
+	assert.Equal(t, []string{"Monday"}, details.Conflicts[0].Days)
+
+	_, err = client.PutRates(context.Background(), &ratespb.PutRatesRequest{Rates: overlapping, AllowOverlap: "cheapest"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err := client.PutRates(context.Background(), &ratespb.PutRatesRequest{
+		Rates: []*ratespb.RateDetail{
+			{Days: "mon", Times: "0900-1700", Tz: "America/Chicago", Pricing: &ratespb.Pricing{PerIncrement: 200}},
+		},
+		Author: "pricing",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "success", resp.Status)
+	// The new rate is numbered after the seed rates
+	assert.Equal(t, IncomingRates{
+		Rates: []RateDetail{
+			{ID: "6", Days: "mon", Times: "0900-1700", TZ: "America/Chicago", Pricing: &Pricing{PerIncrement: 200}},
+		},
+		Author: "pricing",
+	}, a.List())
+
+	// Overrides are put with the rates
+	_, err = client.PutRates(context.Background(), &ratespb.PutRatesRequest{
+		Overrides: []*ratespb.Override{{
+			Name:   "Christmas",
+			Annual: "12-25",
+			Tz:     "America/Chicago",
+			Rates:  []*ratespb.OverrideRate{{Times: "0000-2400", Pricing: &ratespb.Pricing{PerIncrement: 300}}},
+		}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []Override{{
+		Name:   "Christmas",
+		Annual: "12-25",
+		TZ:     "America/Chicago",
+		Rates:  []OverrideRate{{Times: "0000-2400", Pricing: &Pricing{PerIncrement: 300}}},
+	}}, a.List().Overrides)
+
+	// Any other error is an internal error
+	client, stopFailing := newGRPCClient(t, &mockService{err: errors.New("Simulating error setting new rates")})
+	defer stopFailing()
+	_, err = client.PutRates(context.Background(), &ratespb.PutRatesRequest{})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestGRPCListOverrides(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	client, stop := newGRPCClient(t, a)
+	defer stop()
+
+	overrides := []*ratespb.Override{
+		{Name: "Christmas", Annual: "12-25", Tz: "America/Chicago"},
+		{
+			Name:  "Stadium event",
+			Date:  "2020-04-03",
+			Tz:    "America/Chicago",
+			Rates: []*ratespb.OverrideRate{{Times: "1600-2359", Price: 4000, Pricing: &ratespb.Pricing{PerIncrement: 300}}},
+		},
+	}
+	_, err = client.PutRates(context.Background(), &ratespb.PutRatesRequest{Overrides: overrides})
+	assert.Nil(t, err)
+
+	// assert that the overrides that were put are read back in the same shape
+	stream, err := client.ListOverrides(context.Background(), &ratespb.ListRatesRequest{})
+	assert.Nil(t, err)
+	var listed []*ratespb.Override
+	for {
+		o, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		listed = append(listed, o)
+	}
+	assert.Len(t, listed, 2)
+	for i := range overrides {
+		assert.True(t, proto.Equal(overrides[i], listed[i]), overrides[i].Name)
+	}
+}
+
+func TestGRPCListRates(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	client, stop := newGRPCClient(t, a)
+	defer stop()
+
+	stream, err := client.ListRates(context.Background(), &ratespb.ListRatesRequest{})
+	assert.Nil(t, err)
+	var rates []*ratespb.RateDetail
+	for {
+		r, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		rates = append(rates, r)
+	}
+	assert.Len(t, rates, 5)
+	assert.Equal(t, "1", rates[0].Id)
+	assert.Equal(t, "mon,tues,thurs", rates[0].Days)
+	assert.Equal(t, "0900-2100", rates[0].Times)
+	assert.Equal(t, "America/Chicago", rates[0].Tz)
+	assert.Equal(t, int64(1500), rates[0].Price)
+}
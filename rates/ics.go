@@ -0,0 +1,129 @@
+package rates
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ParseICS reads the events of an iCalendar (.ics) holiday list as overrides in tz with the rates.
+// Every event is taken as an all day event named after its SUMMARY. An event that lasts several days,
+// by its DTEND, is an override of that range of dates and an event that recurs yearly, by its RRULE,
+// is an annual override. Any other properties and components are ignored.
+func ParseICS(r io.Reader, tz string, rates []OverrideRate) ([]Override, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+	overrides := []Override{}
+	var event map[string]string
+	for _, line := range lines {
+		name, value := splitICSLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = make(map[string]string)
+		case name == "END" && value == "VEVENT":
+			if event == nil {
+				return nil, errors.New("END:VEVENT without BEGIN:VEVENT")
+			}
+			o, err := icsOverride(event, tz, rates)
+			if err != nil {
+				return nil, err
+			}
+			overrides = append(overrides, o)
+			event = nil
+		case event != nil:
+			event[name] = value
+		}
+	}
+	if event != nil {
+		return nil, errors.New("BEGIN:VEVENT without END:VEVENT")
+	}
+	return overrides, nil
+}
+
+// unfoldICS returns the content lines of an iCalendar file, where a line that starts
+// with a space or a tab continues the line before it
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitICSLine splits a content line into the name of its property, without any parameters, and its value
+func splitICSLine(line string) (name, value string) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return strings.ToUpper(line), ""
+	}
+	name = line[:i]
+	if j := strings.Index(name, ";"); j >= 0 {
+		name = name[:j]
+	}
+	return strings.ToUpper(name), line[i+1:]
+}
+
+// icsOverride returns the override of the properties of an event
+func icsOverride(event map[string]string, tz string, rates []OverrideRate) (Override, error) {
+	start, err := icsDate(event["DTSTART"])
+	if err != nil {
+		return Override{}, err
+	}
+	o := Override{
+		Name:  unescapeICS(event["SUMMARY"]),
+		TZ:    tz,
+		Rates: rates,
+	}
+	if o.Name == "" {
+		o.Name = start.Format("2006-01-02")
+	}
+	if strings.Contains(strings.ToUpper(event["RRULE"]), "FREQ=YEARLY") {
+		o.Annual = start.Format("01-02")
+		return o, nil
+	}
+	o.Date = start.Format("2006-01-02")
+	if dtend, ok := event["DTEND"]; ok {
+		end, err := icsDate(dtend)
+		if err != nil {
+			return Override{}, err
+		}
+		// DTEND is the first date after an all day event
+		last := end.AddDate(0, 0, -1)
+		if last.After(start) {
+			o.Date = ""
+			o.From = start.Format("2006-01-02")
+			o.To = last.Format("2006-01-02")
+		}
+	}
+	return o, nil
+}
+
+// icsDate parses the date of a DATE or DATE-TIME value, such as 20201225 or 20201225T000000Z
+func icsDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("event date must be in the format YYYYMMDD: %q", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("event date must be in the format YYYYMMDD: %q", value)
+	}
+	return date, nil
+}
+
+// unescapeICS unescapes the commas, semicolons, backslashes and newlines of a text value
+func unescapeICS(value string) string {
+	return strings.NewReplacer(`\,`, ",", `\;`, ";", `\\`, `\`, `\n`, " ", `\N`, " ").Replace(value)
+}
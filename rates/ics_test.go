@@ -0,0 +1,79 @@
+package rates
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseICS(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Holidays//EN",
+		"BEGIN:VEVENT",
+		"UID:1@holidays",
+		"DTSTART;VALUE=DATE:20201225",
+		"DTEND;VALUE=DATE:20201226",
+		"RRULE:FREQ=YEARLY;BYMONTH=12;BYMONTHDAY=25",
+		"SUMMARY:Christmas Day",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20201126",
+		"DTEND;VALUE=DATE:20201128",
+		"SUMMARY:Thanksgiving\\, and the day",
+		"  after",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20200704T000000Z",
+		"SUMMARY:Independence Day",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20200907",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	rates := []OverrideRate{{Times: "0000-2400", Price: 3000}}
+
+	overrides, err := ParseICS(strings.NewReader(ics), "America/Chicago", rates)
+	assert.Nil(t, err)
+	assert.Equal(t, []Override{
+		{Name: "Christmas Day", Annual: "12-25", TZ: "America/Chicago", Rates: rates},
+		{Name: "Thanksgiving, and the day after", From: "2020-11-26", To: "2020-11-27", TZ: "America/Chicago", Rates: rates},
+		{Name: "Independence Day", Date: "2020-07-04", TZ: "America/Chicago", Rates: rates},
+		{Name: "2020-09-07", Date: "2020-09-07", TZ: "America/Chicago", Rates: rates},
+	}, overrides)
+
+	testCases := []struct {
+		name string
+		ics  string
+		err  error
+	}{
+		{
+			name: "Event without a date",
+			ics:  "BEGIN:VEVENT\nSUMMARY:Someday\nEND:VEVENT",
+			err:  errors.New(`event date must be in the format YYYYMMDD: ""`),
+		},
+		{
+			name: "Event with a malformed date",
+			ics:  "BEGIN:VEVENT\nDTSTART;VALUE=DATE:2020-12-25\nEND:VEVENT",
+			err:  errors.New(`event date must be in the format YYYYMMDD: "2020-12-25"`),
+		},
+		{
+			name: "Event that isn't ended",
+			ics:  "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20201225",
+			err:  errors.New("BEGIN:VEVENT without END:VEVENT"),
+		},
+		{
+			name: "Event that isn't begun",
+			ics:  "DTSTART;VALUE=DATE:20201225\nEND:VEVENT",
+			err:  errors.New("END:VEVENT without BEGIN:VEVENT"),
+		},
+	}
+	for _, tt := range testCases {
+		_, err := ParseICS(strings.NewReader(tt.ics), "UTC", nil)
+		assert.Equal(t, tt.err, err, tt.name)
+	}
+}
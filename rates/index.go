@@ -0,0 +1,106 @@
+package rates
+
+import "sort"
+
+// indexKey identifies the rates of a single weekday in a single timezone
+type indexKey struct {
+	day string
+	tz  string
+}
+
+// intervalTree is a centered interval tree of the rates of a weekday in a timezone. It finds the rates
+// that contain a time range by walking down a single path of the tree, instead of scanning every rate
+// of the weekday, so the cost of a lookup barely grows with the number of rates.
+type intervalTree struct {
+	center int
+	// byStart and byEnd hold the rates that contain center, sorted by their start time and by
+	// their end time from the latest. The rates that end before center are kept in left and
+	// the rates that start after center are kept in right.
+	byStart []*DayRate
+	byEnd   []*DayRate
+	left    *intervalTree
+	right   *intervalTree
+}
+
+// indexRates builds the interval tree of every weekday and timezone of the rates in m
+func indexRates(m map[string][]DayRate) map[indexKey]*intervalTree {
+	grouped := make(map[indexKey][]*DayRate)
+	for day, rates := range m {
+		for i := range rates {
+			key := indexKey{day: day, tz: rates[i].loc.String()}
+			grouped[key] = append(grouped[key], &rates[i])
+		}
+	}
+	index := make(map[indexKey]*intervalTree)
+	for key, rates := range grouped {
+		index[key] = newIntervalTree(rates)
+	}
+	return index
+}
+
+// newIntervalTree builds the tree of rates around the median of their start and end times
+func newIntervalTree(rates []*DayRate) *intervalTree {
+	if len(rates) == 0 {
+		return nil
+	}
+	times := make([]int, 0, 2*len(rates))
+	for _, r := range rates {
+		times = append(times, r.startTime, r.endTime)
+	}
+	sort.Ints(times)
+	t := &intervalTree{center: times[len(times)/2]}
+
+	var left, right []*DayRate
+	for _, r := range rates {
+		switch {
+		case r.endTime < t.center:
+			left = append(left, r)
+		case r.startTime > t.center:
+			right = append(right, r)
+		default:
+			t.byStart = append(t.byStart, r)
+		}
+	}
+	t.byEnd = make([]*DayRate, len(t.byStart))
+	copy(t.byEnd, t.byStart)
+	sort.Slice(t.byStart, func(i, j int) bool {
+		return t.byStart[i].startTime < t.byStart[j].startTime
+	})
+	sort.Slice(t.byEnd, func(i, j int) bool {
+		return t.byEnd[i].endTime > t.byEnd[j].endTime
+	})
+	t.left = newIntervalTree(left)
+	t.right = newIntervalTree(right)
+	return t
+}
+
+// containing appends the rates that contain the wall clock time range from start to end to found
+func (t *intervalTree) containing(start, end int, found []*DayRate) []*DayRate {
+	// Every rate that contains the time range contains its start, and the rates that contain
+	// the start are all on the path from the root towards the start
+	for t != nil {
+		switch {
+		case start < t.center:
+			// The rates of the node all end at or after center, so only their start has to be checked
+			for _, r := range t.byStart {
+				if r.startTime > start {
+					break
+				}
+				if r.endTime >= end {
+					found = append(found, r)
+				}
+			}
+			t = t.left
+		default:
+			// The rates of the node all start at or before center, so only their end has to be checked
+			for _, r := range t.byEnd {
+				if r.endTime < end {
+					break
+				}
+				found = append(found, r)
+			}
+			t = t.right
+		}
+	}
+	return found
+}
@@ -0,0 +1,76 @@
+package rates
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexRates(t *testing.T) {
+	tbl, err := newTable(IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon", Times: "1200-2100", TZ: "America/Chicago", Price: 1500},
+			{Days: "mon,tues", Times: "0100-0900", TZ: "America/Chicago", Price: 1000},
+			{Days: "mon", Times: "0100-0500", TZ: "UTC", Price: 900},
+			{Days: "mon", Times: "0400-0600", TZ: "America/Chicago", Price: 800},
+			{Days: "mon", Times: "0000-2400", TZ: "America/Chicago", Price: 3000},
+		},
+		AllowOverlap: OverlapLowest,
+	})
+	assert.Nil(t, err)
+	assert.Len(t, tbl.index, 3)
+
+	// indexes returns the indexes of the rates of the tree that contain the time range, in the order of the rates
+	indexes := func(tree *intervalTree, start, end int) []int {
+		found := []int{}
+		for _, r := range tree.containing(start, end, nil) {
+			found = append(found, r.index)
+		}
+		sort.Ints(found)
+		return found
+	}
+	hour := 3600
+	monday := tbl.index[indexKey{day: "Monday", tz: "America/Chicago"}]
+	assert.Equal(t, []int{4}, indexes(monday, 0, hour))
+	assert.Equal(t, []int{1, 4}, indexes(monday, hour, 3*hour))
+	assert.Equal(t, []int{1, 3, 4}, indexes(monday, 4*hour, 5*hour))
+	assert.Equal(t, []int{1, 4}, indexes(monday, 6*hour, 9*hour))
+	assert.Equal(t, []int{4}, indexes(monday, 8*hour, 13*hour))
+	assert.Equal(t, []int{0, 4}, indexes(monday, 21*hour, 21*hour))
+	assert.Equal(t, []int{4}, indexes(monday, 21*hour, 24*hour))
+	assert.Equal(t, []int{1}, indexes(tbl.index[indexKey{day: "Tuesday", tz: "America/Chicago"}], hour, 9*hour))
+	assert.Equal(t, []int{2}, indexes(tbl.index[indexKey{day: "Monday", tz: "UTC"}], hour, 5*hour))
+	assert.Nil(t, tbl.index[indexKey{day: "Tuesday", tz: "UTC"}])
+}
+
+func TestGetRateFromIndex(t *testing.T) {
+	a := &API{}
+	err := a.Put(IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon", Times: "0000-2400", TZ: "UTC", Price: 3000},
+			{Days: "mon", Times: "0900-1000", TZ: "UTC", Price: 1000},
+			{Days: "mon", Times: "1000-1100", TZ: "UTC", Price: 1100},
+			{Days: "mon", Times: "0930-1030", TZ: "UTC", Price: 500},
+		},
+		AllowOverlap: OverlapLowest,
+	})
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		name       string
+		start, end time.Time
+		rate       int
+	}{
+		{"Within a single window", time.Date(2020, 4, 6, 9, 0, 0, 0, time.UTC), time.Date(2020, 4, 6, 9, 20, 0, 0, time.UTC), 1000},
+		{"Within overlapping windows", time.Date(2020, 4, 6, 9, 45, 0, 0, time.UTC), time.Date(2020, 4, 6, 10, 15, 0, 0, time.UTC), 500},
+		{"Within a window that starts earlier than the others", time.Date(2020, 4, 6, 8, 0, 0, 0, time.UTC), time.Date(2020, 4, 6, 10, 45, 0, 0, time.UTC), 3000},
+		{"Within a window that ends at the boundary", time.Date(2020, 4, 6, 10, 30, 0, 0, time.UTC), time.Date(2020, 4, 6, 11, 0, 0, 0, time.UTC), 1100},
+	}
+	for _, tt := range testCases {
+		rate, err := a.Get(ParkingTimesRequest{StartTime: tt.start, EndTime: tt.end})
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, tt.rate, rate, tt.name)
+	}
+}
@@ -1,7 +1,61 @@
 package rates
 
+import "time"
+
 // Service defines the interface to get rates for a given time range
 type Service interface {
 	Get(ParkingTimesRequest) (rate int, err error)
+	Quote(ParkingTimesRequest) (Quote, error)
+	QuoteBatch([]ParkingTimesRequest) []QuoteResult
+	Search(day time.Time, d time.Duration) ([]Candidate, error)
+	Calendar(week time.Time) []CalendarDay
 	Put(ir IncomingRates) error
+	Diff(ir IncomingRates) (RateDiff, error)
+	Patch(p RatePatch) (IncomingRates, error)
+	List() IncomingRates
+	Pending() []IncomingRates
+	Versions() []Version
+	Version(id int) (Version, error)
+	Rollback(id int, author string, caller Caller) (Version, error)
+	Audit(since time.Time) ([]AuditRecord, error)
+}
+
+// FacilityService defines the interface to manage facilities, each with a separate rate table
+// Rates returns the Service that holds the rate table of a facility
+type FacilityService interface {
+	CreateFacility(f Facility) (Facility, error)
+	Facilities() []Facility
+	Facility(id string) (Facility, error)
+	UpdateFacility(f Facility) (Facility, error)
+	DeleteFacility(id string) error
+	Rates(id string) (Service, error)
+}
+
+// RateStore defines the interface to persist the accepted rates across restarts
+// The rates are saved as a schedule: the active rates followed by the pending rates in the order they take effect
+// Load returns ErrNoRates when no rates have been saved yet
+type RateStore interface {
+	Load() ([]IncomingRates, error)
+	Save(schedule []IncomingRates) error
+}
+
+// FacilityStore defines the interface to persist the facilities across restarts
+// LoadFacilities returns no facilities when none have been saved yet
+type FacilityStore interface {
+	LoadFacilities() ([]Facility, error)
+	SaveFacilities(facilities []Facility) error
+}
+
+// VersionLog defines the interface to persist the versions of the rates across restarts
+// Versions are only ever appended. Load returns every version, oldest first
+type VersionLog interface {
+	Append(v Version) error
+	Load() ([]Version, error)
+}
+
+// AuditLog defines the interface to keep a record of every accepted set of rates
+// Records are only ever appended. Since returns the records from since onwards, oldest first
+type AuditLog interface {
+	Append(r AuditRecord) error
+	Since(since time.Time) ([]AuditRecord, error)
 }
@@ -0,0 +1,65 @@
+package rates
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/golang/protobuf/proto"
+)
+
+// negotiableFormats are the formats that responses can be rendered in, the first is the default
+var negotiableFormats = []string{binding.MIMEJSON, binding.MIMEXML, binding.MIMEXML2, binding.MIMEPROTOBUF}
+
+// negotiateFormat returns the format that the response should be rendered in according to the
+// Accept header of the request. If none of the accepted formats are supported, it responds with
+// a 406 in JSON and returns false.
+func negotiateFormat(c *gin.Context) (string, bool) {
+	format := c.NegotiateFormat(negotiableFormats...)
+	if format == "" {
+		c.JSON(406, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("unsupported accept header: %s", c.GetHeader("Accept")),
+		})
+		return "", false
+	}
+	return format, true
+}
+
+// render writes the response in the negotiated format
+// Responses that have no protobuf message are written as JSON
+func render(c *gin.Context, format string, code int, resp interface{}) {
+	switch format {
+	case binding.MIMEXML, binding.MIMEXML2:
+		c.XML(code, resp)
+	case binding.MIMEPROTOBUF:
+		if pb := toProto(resp); pb != nil {
+			c.ProtoBuf(code, pb)
+			return
+		}
+		c.JSON(code, resp)
+	default:
+		c.JSON(code, resp)
+	}
+}
+
+// toProto converts a response to its protobuf message. It returns nil if the response has none.
+func toProto(resp interface{}) proto.Message {
+	switch r := resp.(type) {
+	case RateResponse:
+		return toProtoRateResponse(r)
+	case PutResponse:
+		return toProtoPutResponse(r)
+	}
+	return nil
+}
+
+// bindingFor returns the binding for the Content-Type of the request body
+// XML bodies are bound as XML and every other body is bound as JSON
+func bindingFor(c *gin.Context) binding.Binding {
+	switch c.ContentType() {
+	case binding.MIMEXML, binding.MIMEXML2:
+		return binding.XML
+	}
+	return binding.JSON
+}
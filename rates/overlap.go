@@ -0,0 +1,176 @@
+package rates
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// OverlapLowest resolves overlapping rates by returning the lowest price among them
+	OverlapLowest = "lowest"
+	// OverlapHighest resolves overlapping rates by returning the highest price among them
+	OverlapHighest = "highest"
+)
+
+// referenceYear is the year whose weeks rates are resolved against to check for overlaps
+const referenceYear = 2020
+
+// referenceWeeks returns the Mondays of the weeks of the reference year that rates in the timezones of
+// locations are resolved against to check for overlaps. A week is picked for every distinct combination
+// of the offsets of the timezones over the week, so rates in different timezones are compared with and
+// without daylight saving time in effect, including while the timezones change their clocks on different dates.
+func referenceWeeks(locations []*time.Location) []time.Time {
+	var weeks []time.Time
+	seen := make(map[string]bool)
+	// The first Monday of the reference year
+	for week := time.Date(referenceYear, 1, 6, 0, 0, 0, 0, time.UTC); week.Year() == referenceYear; week = week.AddDate(0, 0, 7) {
+		// The offsets are sampled every hour of the week and of the midnight that ends it, where the rates of Sunday end
+		var signature strings.Builder
+		for h := 0; h <= 7*24; h++ {
+			tm := week.Add(time.Duration(h) * time.Hour)
+			for _, loc := range locations {
+				_, offset := tm.In(loc).Zone()
+				signature.WriteString(strconv.Itoa(offset))
+				signature.WriteByte(',')
+			}
+		}
+		if !seen[signature.String()] {
+			seen[signature.String()] = true
+			weeks = append(weeks, week)
+		}
+	}
+	return weeks
+}
+
+// weekdayOffset is the number of days from Monday for each weekday
+var weekdayOffset = map[string]int{
+	"Monday":    0,
+	"Tuesday":   1,
+	"Wednesday": 2,
+	"Thursday":  3,
+	"Friday":    4,
+	"Saturday":  5,
+	"Sunday":    6,
+}
+
+// Conflict holds a pair of overlapping rates by their index in IncomingRates
+// and the weekdays of the first rate that they overlap on
+type Conflict struct {
+	First  int      `json:"first" xml:"first"`
+	Second int      `json:"second" xml:"second"`
+	Days   []string `json:"days" xml:"days>day"`
+}
+
+// OverlapError is returned when rates overlap and no policy to resolve the overlap was given
+type OverlapError struct {
+	Conflicts []Conflict
+}
+
+func (e *OverlapError) Error() string {
+	return fmt.Sprintf("rates overlap: %d conflicting pairs of rates", len(e.Conflicts))
+}
+
+// validOverlapPolicy checks if policy is one of the supported policies for resolving overlapping rates
+func validOverlapPolicy(policy string) bool {
+	return policy == "" || policy == OverlapLowest || policy == OverlapHighest
+}
+
+// preferredPrice reports if candidate should replace current as the price of overlapping rates
+func preferredPrice(policy string, candidate, current int) bool {
+	switch policy {
+	case OverlapLowest:
+		return candidate < current
+	case OverlapHighest:
+		return candidate > current
+	}
+	return false
+}
+
+// span is a rate resolved to an absolute time range
+type span struct {
+	start time.Time
+	end   time.Time
+	rate  DayRate
+}
+
+// findConflicts returns every pair of rates that overlap on the same instant once they
+// are resolved to absolute time ranges in each of the reference weeks of their timezones.
+// Back to back rates only conflict when both of them include the instant where they meet by their boundary,
+// so with the default half open boundary they never do.
+func findConflicts(m map[string][]DayRate) []Conflict {
+	type pair struct{ first, second int }
+	found := make(map[pair]map[string]bool)
+	var pairs []pair
+
+	var locations []*time.Location
+	for _, rates := range m {
+		for _, r := range rates {
+			locations = appendLocation(locations, r.loc)
+		}
+	}
+	for _, week := range referenceWeeks(locations) {
+		var spans []span
+		for _, rates := range m {
+			for _, r := range rates {
+				y, mon, d := week.AddDate(0, 0, weekdayOffset[r.day]).Date()
+				spans = append(spans, span{
+					start: time.Date(y, mon, d, 0, 0, r.startTime, 0, r.loc),
+					end:   time.Date(y, mon, d, 0, 0, r.endTime, 0, r.loc),
+					rate:  r,
+				})
+			}
+		}
+		// Sort the spans by their start so that each span only has to be compared with the
+		// spans after it until one starts after it ends
+		sort.Slice(spans, func(i, j int) bool {
+			return spans[i].start.Before(spans[j].start)
+		})
+		for i, s := range spans {
+			for _, o := range spans[i+1:] {
+				if o.start.After(s.end) {
+					break
+				}
+				if o.start.Equal(s.end) && !(s.rate.includesEnd() && o.rate.includesStart()) {
+					continue
+				}
+				if s.rate.index == o.rate.index {
+					continue
+				}
+				first, second := s.rate, o.rate
+				if first.index > second.index {
+					first, second = second, first
+				}
+				p := pair{first.index, second.index}
+				days, ok := found[p]
+				if !ok {
+					days = make(map[string]bool)
+					found[p] = days
+					pairs = append(pairs, p)
+				}
+				days[first.day] = true
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].first != pairs[j].first {
+			return pairs[i].first < pairs[j].first
+		}
+		return pairs[i].second < pairs[j].second
+	})
+	var conflicts []Conflict
+	for _, p := range pairs {
+		c := Conflict{First: p.first, Second: p.second}
+		// List the weekdays in the order of the week
+		for _, day := range []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"} {
+			if found[p][day] {
+				c.Days = append(c.Days, day)
+			}
+		}
+		conflicts = append(conflicts, c)
+	}
+	return conflicts
+}
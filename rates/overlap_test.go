@@ -0,0 +1,169 @@
+package rates
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutRejectsOverlappingRates(t *testing.T) {
+	testCases := []struct {
+		name      string
+		rates     []RateDetail
+		conflicts []Conflict
+	}{
+		{
+			name: "No overlap between back to back rates",
+			rates: []RateDetail{
+				{Days: "mon", Times: "0900-1200", TZ: "America/Chicago", Price: 1000},
+				{Days: "mon", Times: "1200-1800", TZ: "America/Chicago", Price: 1500},
+			},
+			conflicts: nil,
+		},
+		{
+			name: "No overlap between back to back rates when only one includes the instant they meet at",
+			rates: []RateDetail{
+				{Days: "mon", Times: "0900-1200", TZ: "America/Chicago", Price: 1000, Boundary: BoundaryClosed},
+				{Days: "mon", Times: "1200-1800", TZ: "America/Chicago", Price: 1500, Boundary: BoundaryOpen},
+				{Days: "mon", Times: "1800-2100", TZ: "America/Chicago", Price: 1500, Boundary: BoundaryOpenStart},
+			},
+			conflicts: nil,
+		},
+		{
+			name: "Overlap between back to back rates that both include the instant they meet at",
+			rates: []RateDetail{
+				{Days: "mon", Times: "0900-1200", TZ: "America/Chicago", Price: 1000, Boundary: BoundaryOpenStart},
+				{Days: "mon", Times: "1200-1800", TZ: "America/Chicago", Price: 1500},
+			},
+			conflicts: []Conflict{
+				{First: 0, Second: 1, Days: []string{"Monday"}},
+			},
+		},
+		{
+			name: "Overlap on the same weekday",
+			rates: []RateDetail{
+				{Days: "mon,tues", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+				{Days: "wed", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+				{Days: "mon", Times: "1000-1200", TZ: "America/Chicago", Price: 2000},
+			},
+			conflicts: []Conflict{
+				{First: 0, Second: 2, Days: []string{"Monday"}},
+			},
+		},
+		{
+			name: "Every conflicting pair is listed",
+			rates: []RateDetail{
+				{Days: "mon,tues", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+				{Days: "mon,tues", Times: "1000-1200", TZ: "America/Chicago", Price: 2000},
+				{Days: "tues", Times: "1100-1300", TZ: "America/Chicago", Price: 2500},
+			},
+			conflicts: []Conflict{
+				{First: 0, Second: 1, Days: []string{"Monday", "Tuesday"}},
+				{First: 0, Second: 2, Days: []string{"Tuesday"}},
+				{First: 1, Second: 2, Days: []string{"Tuesday"}},
+			},
+		},
+		{
+			name: "Overlap after timezone normalization",
+			rates: []RateDetail{
+				{Days: "mon", Times: "0900-1200", TZ: "America/Chicago", Price: 1500},
+				{Days: "mon", Times: "1700-1900", TZ: "UTC", Price: 2000},
+			},
+			conflicts: []Conflict{
+				{First: 0, Second: 1, Days: []string{"Monday"}},
+			},
+		},
+		{
+			name: "Overlap across weekdays after timezone normalization",
+			rates: []RateDetail{
+				{Days: "mon", Times: "2000-2400", TZ: "America/Chicago", Price: 1500},
+				{Days: "tues", Times: "0000-0300", TZ: "UTC", Price: 2000},
+			},
+			conflicts: []Conflict{
+				{First: 0, Second: 1, Days: []string{"Monday"}},
+			},
+		},
+		{
+			name: "Overlap only while the timezones change their clocks on different dates",
+			rates: []RateDetail{
+				{Days: "mon", Times: "0000-1300", TZ: "Europe/London", Price: 1},
+				{Days: "mon", Times: "0700-2400", TZ: "America/Chicago", Price: 2},
+			},
+			conflicts: []Conflict{
+				{First: 0, Second: 1, Days: []string{"Monday"}},
+			},
+		},
+	}
+	for _, tt := range testCases {
+		a, err := NewAPI("seed_rates.json")
+		assert.Nil(t, err)
+
+		err = a.Put(IncomingRates{Rates: tt.rates})
+		if tt.conflicts == nil {
+			assert.Nil(t, err, tt.name)
+			continue
+		}
+		var overlapErr *OverlapError
+		assert.True(t, errors.As(err, &overlapErr), tt.name)
+		assert.Equal(t, tt.conflicts, overlapErr.Conflicts, tt.name)
+		// assert that the rejected rates were not put in place
+		assert.Len(t, a.List().Rates, 5, tt.name)
+	}
+}
+
+func TestReferenceWeeks(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	assert.Nil(t, err)
+	london, err := time.LoadLocation("Europe/London")
+	assert.Nil(t, err)
+
+	// A timezone without daylight saving time only needs a single week
+	assert.Equal(t, []time.Time{time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)}, referenceWeeks([]*time.Location{time.UTC}))
+
+	// Chicago moves its clocks forward on March 8 and London on March 29, so a week in between is checked
+	weeks := referenceWeeks([]*time.Location{chicago, london})
+	assert.Contains(t, weeks, time.Date(2020, 3, 9, 0, 0, 0, 0, time.UTC))
+	for _, week := range weeks {
+		assert.Equal(t, time.Monday, week.Weekday())
+		assert.Equal(t, referenceYear, week.Year())
+	}
+}
+
+func TestPutWithOverlapPolicy(t *testing.T) {
+	rates := []RateDetail{
+		{Days: "mon", Times: "0900-2100", TZ: "UTC", Price: 1500},
+		{Days: "mon", Times: "1000-1200", TZ: "UTC", Price: 2000},
+		{Days: "mon", Times: "1000-1300", TZ: "UTC", Price: 1000},
+	}
+	p := ParkingTimesRequest{
+		StartTime: time.Date(2020, 4, 6, 10, 30, 0, 0, time.UTC),
+		EndTime:   time.Date(2020, 4, 6, 11, 30, 0, 0, time.UTC),
+	}
+	testCases := []struct {
+		name   string
+		policy string
+		rate   int
+	}{
+		{name: "Lowest", policy: OverlapLowest, rate: 1000},
+		{name: "Highest", policy: OverlapHighest, rate: 2000},
+	}
+	for _, tt := range testCases {
+		a, err := NewAPI("seed_rates.json")
+		assert.Nil(t, err)
+
+		err = a.Put(IncomingRates{Rates: rates, AllowOverlap: tt.policy})
+		assert.Nil(t, err, tt.name)
+		rate, err := a.Get(p)
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, tt.rate, rate, tt.name)
+		// assert that the policy is returned with the active rates
+		assert.Equal(t, tt.policy, a.List().AllowOverlap, tt.name)
+	}
+
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	err = a.Put(IncomingRates{Rates: rates, AllowOverlap: "newest"})
+	assert.Equal(t, errors.New("unknown overlap policy: newest"), err)
+}
@@ -0,0 +1,258 @@
+package rates
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// overrideDate, overrideRange and overrideAnnual are the kinds of override, from the most specific
+	overrideDate = iota
+	overrideRange
+	overrideAnnual
+)
+
+// Override replaces the weekday rates of every timezone with its own rates on particular dates, such as
+// a holiday or an event day. It applies either to a single Date, to every date from From to To or to the
+// Annual date of every year, each from midnight to midnight in its timezone. Dates are written as 2006-01-02
+// and annual dates as 01-02. An override without any rates closes the dates it applies to.
+type Override struct {
+	Name   string         `json:"name" xml:"name"`
+	Date   string         `json:"date,omitempty" xml:"date,omitempty"`
+	From   string         `json:"from,omitempty" xml:"from,omitempty"`
+	To     string         `json:"to,omitempty" xml:"to,omitempty"`
+	Annual string         `json:"annual,omitempty" xml:"annual,omitempty"`
+	TZ     string         `json:"tz" xml:"tz"`
+	Rates  []OverrideRate `json:"rates" xml:"rate"`
+}
+
+// OverrideRate is a window of an override. It is priced like a RateDetail on the dates of the override.
+type OverrideRate struct {
+	Times    string   `json:"times" xml:"times"`
+	Price    int      `json:"price" xml:"price"`
+	Pricing  *Pricing `json:"pricing,omitempty" xml:"pricing,omitempty"`
+	Boundary string   `json:"boundary,omitempty" xml:"boundary,omitempty"`
+}
+
+// dateOverride is an Override with its dates parsed and its rates indexed
+type dateOverride struct {
+	name string
+	kind int
+	// from and to are the first and last dates of the override as 20060102, or the annual date as 0102
+	from int
+	to   int
+	loc  *time.Location
+	// rates holds the windows of the override. They don't belong to a weekday, so their day is empty.
+	rates []DayRate
+	tree  *intervalTree
+}
+
+// newOverrides parses the overrides and builds the index of their rates.
+// The overrides are ordered by how specific they are, so that a single date takes precedence over
+// a range of dates, which takes precedence over an annual date. Overrides of the same kind keep their order.
+func newOverrides(overrides []Override) ([]dateOverride, error) {
+	var parsed []dateOverride
+	for _, o := range overrides {
+		d, err := parseOverride(o)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, d)
+	}
+	sort.SliceStable(parsed, func(i, j int) bool {
+		return parsed[i].kind < parsed[j].kind
+	})
+	return parsed, nil
+}
+
+// parseOverride parses the dates and rates of o
+func parseOverride(o Override) (dateOverride, error) {
+	d := dateOverride{name: o.Name}
+	var err error
+	if d.kind, d.from, d.to, err = parseOverrideDates(o); err != nil {
+		return dateOverride{}, err
+	}
+	if d.loc, err = time.LoadLocation(o.TZ); err != nil {
+		return dateOverride{}, err
+	}
+	for _, r := range o.Rates {
+		start, end, err := parseTimes(r.Times)
+		if err != nil {
+			return dateOverride{}, err
+		}
+		dr := DayRate{
+			startTime: start,
+			endTime:   end,
+			price:     r.Price,
+			tz:        o.TZ,
+			loc:       d.loc,
+			index:     -1,
+			boundary:  r.Boundary,
+			override:  o.Name,
+		}
+		if r.Pricing != nil {
+			pricing := *r.Pricing
+			dr.pricing = &pricing
+		}
+		d.rates = append(d.rates, dr)
+	}
+	rates := make([]*DayRate, len(d.rates))
+	for i := range d.rates {
+		rates[i] = &d.rates[i]
+	}
+	d.tree = newIntervalTree(rates)
+	return d, nil
+}
+
+// parseOverrideDates returns the kind of o and its first and last dates
+func parseOverrideDates(o Override) (kind, from, to int, err error) {
+	switch {
+	case o.Date != "" && o.From == "" && o.To == "" && o.Annual == "":
+		from, err = parseDate(o.Date)
+		return overrideDate, from, from, err
+	case o.Date == "" && o.From != "" && o.To != "" && o.Annual == "":
+		if from, err = parseDate(o.From); err != nil {
+			return 0, 0, 0, err
+		}
+		if to, err = parseDate(o.To); err != nil {
+			return 0, 0, 0, err
+		}
+		if to < from {
+			return 0, 0, 0, fmt.Errorf("to must not be before from: %q", o.To)
+		}
+		return overrideRange, from, to, nil
+	case o.Date == "" && o.From == "" && o.To == "" && o.Annual != "":
+		from, err = parseAnnualDate(o.Annual)
+		return overrideAnnual, from, from, err
+	}
+	return 0, 0, 0, fmt.Errorf("an override needs either a date, a from and to date or an annual date")
+}
+
+// parseDate parses a date in the format 2006-01-02 into 20060102
+func parseDate(date string) (int, error) {
+	tm, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return 0, fmt.Errorf("date must be in the format YYYY-MM-DD: %q", date)
+	}
+	return tm.Year()*10000 + int(tm.Month())*100 + tm.Day(), nil
+}
+
+// parseAnnualDate parses a date of the year in the format 01-02 into 0102. 02-29 is only in force in leap years.
+func parseAnnualDate(date string) (int, error) {
+	// Parse the date in a leap year, so that the 29th of February is accepted
+	tm, err := time.Parse("2006-01-02", "2020-"+date)
+	if err != nil || len(date) != 5 {
+		return 0, fmt.Errorf("annual date must be in the format MM-DD: %q", date)
+	}
+	return int(tm.Month())*100 + tm.Day(), nil
+}
+
+// applies checks if the override is in force on the date of tm in the timezone of tm
+func (o *dateOverride) applies(tm time.Time) bool {
+	y, m, d := tm.Date()
+	if o.kind == overrideAnnual {
+		return int(m)*100+d == o.from
+	}
+	date := y*10000 + int(m)*100 + d
+	return o.from <= date && date <= o.to
+}
+
+// overrideOn returns the override of the table in loc that is in force on the date of tm in loc.
+// It returns nil when no override in loc is in force on that date.
+func (t *rateTable) overrideOn(tm time.Time, loc *time.Location) *dateOverride {
+	local := tm.In(loc)
+	for i := range t.overrides {
+		o := &t.overrides[i]
+		if o.loc.String() == loc.String() && o.applies(local) {
+			return o
+		}
+	}
+	return nil
+}
+
+// overridden checks if an override of the table is in force at any point of the time range from start to end
+// on its date in its own timezone. The weekday rates of every timezone are left out while an override is in force.
+func (t *rateTable) overridden(start, end time.Time) bool {
+	last := start
+	if end.After(start) {
+		last = end.Add(-time.Nanosecond)
+	}
+	for i := range t.overrides {
+		o := &t.overrides[i]
+		if o.applies(start.In(o.loc)) || o.applies(last.In(o.loc)) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateOverrides checks every override of ir for malformed fields and returns them listed by
+// the index of the override. The field of an error is prefixed with overrides.
+func validateOverrides(ir IncomingRates) []FieldError {
+	var errs []FieldError
+	for i, o := range ir.Overrides {
+		add := func(field, format string, args ...interface{}) {
+			errs = append(errs, FieldError{
+				Index:   i,
+				Field:   "overrides." + field,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		if o.Name == "" {
+			add("name", "name is required")
+		}
+		if o.TZ == "" {
+			add("tz", "tz is required")
+		} else if _, err := time.LoadLocation(o.TZ); err != nil {
+			add("tz", "unknown timezone: %q", o.TZ)
+		}
+		if _, _, _, err := parseOverrideDates(o); err != nil {
+			add("date", "%s", err)
+		}
+		var windows []DayRate
+		for _, r := range o.Rates {
+			start, end, err := parseTimes(r.Times)
+			if err != nil {
+				add("rates.times", "%s", err)
+			}
+			if !validBoundary(r.Boundary) {
+				add("rates.boundary", "unknown boundary: %q", r.Boundary)
+			}
+			if r.Price < 0 {
+				add("rates.price", "price must not be negative")
+			}
+			validatePricing(r.Pricing, func(field, format string, args ...interface{}) {
+				add("rates."+field, format, args...)
+			})
+			if err == nil {
+				windows = append(windows, DayRate{startTime: start, endTime: end, boundary: r.Boundary})
+			}
+		}
+		// The rates of an override can only overlap when there is a policy to resolve the overlap
+		if ir.AllowOverlap == "" && windowsOverlap(windows) {
+			add("rates", "the rates of an override must not overlap")
+		}
+	}
+	return errs
+}
+
+// windowsOverlap checks if any of the windows of a single day overlap
+func windowsOverlap(windows []DayRate) bool {
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].startTime < windows[j].startTime
+	})
+	// latest is the window that ends the latest of the windows so far
+	latest := 0
+	for i := 1; i < len(windows); i++ {
+		prev, next := windows[latest], windows[i]
+		if next.startTime < prev.endTime || (next.startTime == prev.endTime && prev.includesEnd() && next.includesStart()) {
+			return true
+		}
+		if next.endTime > prev.endTime || (next.endTime == prev.endTime && next.includesEnd()) {
+			latest = i
+		}
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+package rates
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRateWithOverrides(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	err = a.Put(IncomingRates{
+		Rates: a.List().Rates,
+		Overrides: []Override{
+			{Name: "Christmas", Annual: "12-25", TZ: "America/Chicago", Rates: []OverrideRate{{Times: "0000-2400", Price: 3000}}},
+			{Name: "Stadium event", Date: "2020-12-25", TZ: "America/Chicago", Rates: []OverrideRate{{Times: "1200-2400", Price: 5000}}},
+			{Name: "Closed for works", From: "2020-04-06", To: "2020-04-07", TZ: "America/Chicago", Rates: []OverrideRate{}},
+		},
+	})
+	assert.Nil(t, err)
+	loc, err := time.LoadLocation("America/Chicago")
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+		rate  int
+		err   error
+	}{
+		{
+			name:  "Weekday rates on a date without an override",
+			start: time.Date(2020, 4, 3, 9, 30, 0, 0, loc),
+			end:   time.Date(2020, 4, 3, 14, 30, 0, 0, loc),
+			rate:  2000,
+		},
+		{
+			name:  "Annual override of a date in another year",
+			start: time.Date(2021, 12, 25, 7, 0, 0, 0, loc),
+			end:   time.Date(2021, 12, 25, 8, 0, 0, 0, loc),
+			rate:  3000,
+		},
+		{
+			name:  "A single date takes precedence over an annual date",
+			start: time.Date(2020, 12, 25, 13, 0, 0, 0, loc),
+			end:   time.Date(2020, 12, 25, 15, 0, 0, 0, loc),
+			rate:  5000,
+		},
+		{
+			name:  "The weekday rates don't fill in the times an override doesn't cover",
+			start: time.Date(2020, 12, 25, 9, 0, 0, 0, loc),
+			end:   time.Date(2020, 12, 25, 10, 0, 0, 0, loc),
+			err:   errors.New("unavailable"),
+		},
+		{
+			name:  "An override without rates closes every date of its range",
+			start: time.Date(2020, 4, 7, 9, 30, 0, 0, loc),
+			end:   time.Date(2020, 4, 7, 14, 30, 0, 0, loc),
+			err:   errors.New("unavailable"),
+		},
+		{
+			name:  "The date is taken in the timezone of the override",
+			start: time.Date(2020, 4, 8, 3, 0, 0, 0, time.UTC),
+			end:   time.Date(2020, 4, 8, 4, 0, 0, 0, time.UTC),
+			err:   errors.New("unavailable"),
+		},
+		{
+			name:  "Weekday rates after the range",
+			start: time.Date(2020, 4, 8, 9, 30, 0, 0, loc),
+			end:   time.Date(2020, 4, 8, 14, 30, 0, 0, loc),
+			rate:  1750,
+		},
+	}
+	for _, tt := range testCases {
+		rate, err := a.Get(ParkingTimesRequest{StartTime: tt.start, EndTime: tt.end})
+		assert.Equal(t, tt.err, err, tt.name)
+		assert.Equal(t, tt.rate, rate, tt.name)
+	}
+
+	// The overrides are read back with the rates, and are shown by the calendar
+	assert.Len(t, a.List().Overrides, 3)
+	days := a.Calendar(time.Date(2020, 12, 25, 0, 0, 0, 0, loc))
+	assert.Equal(t, []CalendarInterval{
+		{Start: "12:00", End: "24:00", Index: -1, Override: "Stadium event", Price: 5000},
+	}, days[4].Intervals)
+}
+
+func TestGetRateWithOverrideInAnotherTimezone(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{{Days: "thurs,fri", Times: "0000-2400", TZ: "America/Chicago", Price: 5}},
+		Overrides: []Override{
+			{Name: "xmas", Date: "2020-12-25", TZ: "UTC", Rates: []OverrideRate{}},
+			{Name: "event", Date: "2020-12-18", TZ: "UTC", Rates: []OverrideRate{{Times: "1200-1800", Price: 9}}},
+		},
+	})
+	assert.Nil(t, err)
+	loc, err := time.LoadLocation("America/Chicago")
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+		rate  int
+		err   error
+	}{
+		{
+			name:  "An override closes the weekday rates of every timezone",
+			start: time.Date(2020, 12, 25, 10, 0, 0, 0, loc),
+			end:   time.Date(2020, 12, 25, 12, 0, 0, 0, loc),
+			err:   errors.New("unavailable"),
+		},
+		{
+			name:  "The override is in force from its midnight in its own timezone",
+			start: time.Date(2020, 12, 24, 19, 0, 0, 0, loc),
+			end:   time.Date(2020, 12, 24, 20, 0, 0, 0, loc),
+			err:   errors.New("unavailable"),
+		},
+		{
+			name:  "Weekday rates before the override",
+			start: time.Date(2020, 12, 24, 16, 0, 0, 0, loc),
+			end:   time.Date(2020, 12, 24, 17, 0, 0, 0, loc),
+			rate:  5,
+		},
+		{
+			name:  "The rates of the override take the place of the weekday rates",
+			start: time.Date(2020, 12, 18, 7, 0, 0, 0, loc),
+			end:   time.Date(2020, 12, 18, 8, 0, 0, 0, loc),
+			rate:  9,
+		},
+		{
+			name:  "The weekday rates don't fill in the times the override doesn't cover",
+			start: time.Date(2020, 12, 18, 13, 0, 0, 0, loc),
+			end:   time.Date(2020, 12, 18, 14, 0, 0, 0, loc),
+			err:   errors.New("unavailable"),
+		},
+	}
+	for _, tt := range testCases {
+		rate, err := a.Get(ParkingTimesRequest{StartTime: tt.start, EndTime: tt.end})
+		assert.Equal(t, tt.err, err, tt.name)
+		assert.Equal(t, tt.rate, rate, tt.name)
+	}
+
+	// assert that the weekday windows are cut off where the override is in force
+	spans := a.snapshot()[0].spansBetween(time.Date(2020, 12, 24, 0, 0, 0, 0, loc), time.Date(2020, 12, 25, 0, 0, 0, 0, loc))
+	assert.Len(t, spans, 1)
+	assert.True(t, spans[0].start.Equal(time.Date(2020, 12, 24, 0, 0, 0, 0, loc)))
+	assert.True(t, spans[0].end.Equal(time.Date(2020, 12, 25, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestValidateOverrides(t *testing.T) {
+	testCases := []struct {
+		name         string
+		overrides    []Override
+		allowOverlap string
+		errors       []FieldError
+	}{
+		{
+			name: "Valid overrides",
+			overrides: []Override{
+				{Name: "New Year", Annual: "01-01", TZ: "UTC", Rates: []OverrideRate{{Times: "0000-1200", Price: 1000}, {Times: "1200-2400", Price: 2000}}},
+				{Name: "Leap day", Annual: "02-29", TZ: "UTC"},
+				{Name: "Festival", From: "2020-07-01", To: "2020-07-03", TZ: "UTC", Rates: []OverrideRate{{Times: "09:00:00-17:00:00", Price: 2500}}},
+			},
+		},
+		{
+			name: "Every invalid field is listed by the index of its override",
+			overrides: []Override{
+				{Name: "Christmas", Date: "2020-12-25", TZ: "UTC"},
+				{Date: "2020-12-25", Annual: "12-25", TZ: "Mars/Olympus_Mons"},
+				{Name: "Festival", From: "2020-07-03", To: "2020-07-01", Rates: []OverrideRate{{Times: "0900", Price: -1, Boundary: "inclusive"}}},
+				{Name: "Bad date", Annual: "13-01", TZ: "UTC", Rates: []OverrideRate{{Times: "0900-1700", Pricing: &Pricing{PerIncrement: -1}}}},
+			},
+			errors: []FieldError{
+				{Index: 1, Field: "overrides.name", Message: "name is required"},
+				{Index: 1, Field: "overrides.tz", Message: `unknown timezone: "Mars/Olympus_Mons"`},
+				{Index: 1, Field: "overrides.date", Message: "an override needs either a date, a from and to date or an annual date"},
+				{Index: 2, Field: "overrides.tz", Message: "tz is required"},
+				{Index: 2, Field: "overrides.date", Message: `to must not be before from: "2020-07-01"`},
+				{Index: 2, Field: "overrides.rates.times", Message: `times must be in the format HHMM-HHMM or HH:MM:SS-HH:MM:SS: "0900"`},
+				{Index: 2, Field: "overrides.rates.boundary", Message: `unknown boundary: "inclusive"`},
+				{Index: 2, Field: "overrides.rates.price", Message: "price must not be negative"},
+				{Index: 3, Field: "overrides.date", Message: `annual date must be in the format MM-DD: "13-01"`},
+				{Index: 3, Field: "overrides.rates.pricing.per_increment", Message: "per_increment must not be negative"},
+			},
+		},
+		{
+			name: "Overlapping rates of an override",
+			overrides: []Override{
+				{Name: "Christmas", Date: "2020-12-25", TZ: "UTC", Rates: []OverrideRate{
+					{Times: "0000-2400", Price: 3000},
+					{Times: "1000-1200", Price: 5000},
+					{Times: "1300-1400", Price: 5000},
+				}},
+				{Name: "Boxing Day", Date: "2020-12-26", TZ: "UTC", Rates: []OverrideRate{
+					{Times: "0000-1200", Price: 3000, Boundary: BoundaryClosed},
+					{Times: "1200-2400", Price: 5000},
+				}},
+			},
+			errors: []FieldError{
+				{Index: 0, Field: "overrides.rates", Message: "the rates of an override must not overlap"},
+				{Index: 1, Field: "overrides.rates", Message: "the rates of an override must not overlap"},
+			},
+		},
+		{
+			name: "Overlapping rates of an override with a policy",
+			overrides: []Override{
+				{Name: "Christmas", Date: "2020-12-25", TZ: "UTC", Rates: []OverrideRate{
+					{Times: "0000-2400", Price: 3000},
+					{Times: "1000-1200", Price: 5000},
+				}},
+			},
+			allowOverlap: OverlapHighest,
+		},
+	}
+	for _, tt := range testCases {
+		err := Validate(IncomingRates{Rates: []RateDetail{}, Overrides: tt.overrides, AllowOverlap: tt.allowOverlap})
+		if tt.errors == nil {
+			assert.Nil(t, err, tt.name)
+			continue
+		}
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr), tt.name)
+		assert.Equal(t, tt.errors, validationErr.Errors, tt.name)
+	}
+}
+
+func TestGetRateWithOverlappingOverrideRates(t *testing.T) {
+	a := &API{}
+	err := a.Put(IncomingRates{
+		Overrides: []Override{
+			{Name: "Christmas", Date: "2020-12-25", TZ: "UTC", Rates: []OverrideRate{
+				{Times: "0000-2400", Price: 3000},
+				{Times: "1000-1200", Price: 5000},
+			}},
+		},
+		AllowOverlap: OverlapHighest,
+	})
+	assert.Nil(t, err)
+	rate, err := a.Get(ParkingTimesRequest{
+		StartTime: time.Date(2020, 12, 25, 10, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2020, 12, 25, 11, 0, 0, 0, time.UTC),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 5000, rate)
+}
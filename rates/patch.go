@@ -0,0 +1,106 @@
+package rates
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRateNotFound is returned when a patch updates or removes a rate that isn't among the active rates
+var ErrRateNotFound = errors.New("rate not found")
+
+// RatePatch holds changes to the active rates, where each rate is addressed by its id
+// Add appends new rates, Update replaces the rates with the same ids in full and Remove removes the rates with those ids.
+// Overrides are added to the overrides of the active rates, which are otherwise kept as they are.
+// AllowOverlap is optional and keeps the policy of the active rates when it is empty.
+// Author and Comment are optional and are recorded with the version of the patched rates.
+// Caller is the authenticated client that patched the rates, like in IncomingRates.
+type RatePatch struct {
+	Add          []RateDetail `json:"add,omitempty" xml:"add>rate,omitempty"`
+	Update       []RateDetail `json:"update,omitempty" xml:"update>rate,omitempty"`
+	Remove       []string     `json:"remove,omitempty" xml:"remove>id,omitempty"`
+	Overrides    []Override   `json:"overrides,omitempty" xml:"override,omitempty"`
+	AllowOverlap string       `json:"allow_overlap,omitempty" xml:"allow_overlap,omitempty"`
+	Author       string       `json:"author,omitempty" xml:"author,omitempty"`
+	Comment      string       `json:"comment,omitempty" xml:"comment,omitempty"`
+	Caller       Caller       `json:"-" xml:"-"`
+}
+
+// Patch applies the changes to the active rates and puts the result in place of them, like Put does.
+// The active rates are read, patched and replaced while holding the lock, so no other update can
+// come in between. Pending rates are left as they are. It returns the rates as they are after the patch.
+//
+// The patched rates are validated and checked for overlaps as a whole, so the index in any
+// FieldError or Conflict is the index of the rate after the patch, in the order List returns them:
+// the active rates that weren't removed, followed by the added rates.
+func (a *API) Patch(p RatePatch) (IncomingRates, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	active := IncomingRates{Rates: []RateDetail{}}
+	if t := tableAt(a.snapshot(), time.Now()); t != nil {
+		active = copyRates(t.rates)
+	}
+	ir, err := patchRates(active, p)
+	if err != nil {
+		return IncomingRates{}, err
+	}
+	// The added rates are given ids before they are validated, so that they are validated as they are stored
+	a.assignIDs(ir.Rates)
+	t, err := newTable(ir)
+	if err != nil {
+		return IncomingRates{}, err
+	}
+	v, err := a.apply(t, ir)
+	if err != nil {
+		return IncomingRates{}, err
+	}
+	return v.IncomingRates, nil
+}
+
+// patchRates returns the rates of active with the changes of p applied to them
+// It returns ErrRateNotFound if p updates or removes a rate that active doesn't have.
+func patchRates(active IncomingRates, p RatePatch) (IncomingRates, error) {
+	// index holds the position of each rate of active by its id
+	index := make(map[string]int)
+	for i, r := range active.Rates {
+		index[r.ID] = i
+	}
+	rates := copyRates(active).Rates
+	for _, r := range p.Update {
+		i, ok := index[r.ID]
+		if r.ID == "" || !ok {
+			return IncomingRates{}, fmt.Errorf("%w: %q", ErrRateNotFound, r.ID)
+		}
+		rates[i] = r
+	}
+	removed := make(map[string]bool)
+	for _, id := range p.Remove {
+		if _, ok := index[id]; id == "" || !ok {
+			return IncomingRates{}, fmt.Errorf("%w: %q", ErrRateNotFound, id)
+		}
+		removed[id] = true
+	}
+
+	// The overrides are copied so that the overrides of active are never appended to in place
+	var overrides []Override
+	overrides = append(overrides, active.Overrides...)
+	patched := IncomingRates{
+		Rates:        []RateDetail{},
+		Overrides:    append(overrides, p.Overrides...),
+		AllowOverlap: active.AllowOverlap,
+		Author:       p.Author,
+		Comment:      p.Comment,
+		Caller:       p.Caller,
+	}
+	if p.AllowOverlap != "" {
+		patched.AllowOverlap = p.AllowOverlap
+	}
+	for _, r := range rates {
+		if !removed[r.ID] {
+			patched.Rates = append(patched.Rates, r)
+		}
+	}
+	patched.Rates = append(patched.Rates, p.Add...)
+	return patched, nil
+}
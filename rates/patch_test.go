@@ -0,0 +1,171 @@
+package rates
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	a, err := NewAPIWithOptions("seed_rates.json", Options{Audit: NewFileAuditLog(filepath.Join(dir, "audit.jsonl"))})
+	assert.Nil(t, err)
+	// assert that the seed rates are numbered in the order they were put
+	seed := a.List()
+	for i, r := range seed.Rates {
+		assert.Equal(t, fmt.Sprint(i+1), r.ID)
+	}
+
+	ir, err := a.Patch(RatePatch{
+		Update: []RateDetail{{ID: "1", Days: "mon,tues,thurs", Times: "0900-2100", TZ: "America/Chicago", Price: 1750}},
+		Remove: []string{"4", "5"},
+		Add: []RateDetail{
+			{Days: "sun", Times: "0000-0900", TZ: "America/Chicago", Price: 500},
+			{ID: "night", Days: "mon", Times: "2100-2400", TZ: "America/Chicago", Price: 800},
+		},
+		Author: "pricing",
+		Caller: Caller{Name: "ops", Role: RoleAdmin},
+	})
+	assert.Nil(t, err)
+	expected := []RateDetail{
+		{ID: "1", Days: "mon,tues,thurs", Times: "0900-2100", TZ: "America/Chicago", Price: 1750},
+		seed.Rates[1],
+		seed.Rates[2],
+		{ID: "6", Days: "sun", Times: "0000-0900", TZ: "America/Chicago", Price: 500},
+		{ID: "night", Days: "mon", Times: "2100-2400", TZ: "America/Chicago", Price: 800},
+	}
+	assert.Equal(t, IncomingRates{Rates: expected, Author: "pricing"}, ir)
+	assert.Equal(t, ir, a.List())
+
+	// assert that the patch is priced, recorded as a version and audited like a put
+	rate, err := a.Get(ParkingTimesRequest{
+		StartTime: time.Date(2020, 4, 6, 15, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2020, 4, 6, 16, 0, 0, 0, time.UTC),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1750, rate)
+	assert.Len(t, a.Versions(), 2)
+	records, err := a.Audit(time.Time{})
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "ops", records[0].Caller)
+	assert.Equal(t, seed, records[0].Previous)
+
+	// assert that unknown ids are rejected without changing anything
+	_, err = a.Patch(RatePatch{Update: []RateDetail{{ID: "4", Days: "mon", Times: "0100-0500", TZ: "America/Chicago"}}})
+	assert.True(t, errors.Is(err, ErrRateNotFound))
+	assert.EqualError(t, err, `rate not found: "4"`)
+	_, err = a.Patch(RatePatch{Remove: []string{"1", "missing"}})
+	assert.True(t, errors.Is(err, ErrRateNotFound))
+	_, err = a.Patch(RatePatch{Update: []RateDetail{{Days: "mon", Times: "0100-0500", TZ: "America/Chicago"}}})
+	assert.True(t, errors.Is(err, ErrRateNotFound))
+	assert.Equal(t, ir, a.List())
+
+	// assert that the patched rates are validated by their index after the patch
+	_, err = a.Patch(RatePatch{
+		Remove: []string{"1"},
+		Add:    []RateDetail{{Days: "mon", Times: "0900", TZ: "America/Chicago"}},
+	})
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, []FieldError{
+		{Index: 4, Field: "times", Message: `times must be in the format HHMM-HHMM or HH:MM:SS-HH:MM:SS: "0900"`},
+	}, validationErr.Errors)
+	_, err = a.Patch(RatePatch{Add: []RateDetail{{ID: "night", Days: "tues", Times: "2100-2400", TZ: "America/Chicago"}}})
+	assert.True(t, errors.As(err, &validationErr))
+
+	// assert that overlaps are rejected unless there is a policy to resolve them
+	overlapping := RatePatch{Add: []RateDetail{{Days: "mon", Times: "1200-2200", TZ: "America/Chicago", Price: 1000}}}
+	_, err = a.Patch(overlapping)
+	var overlapErr *OverlapError
+	assert.True(t, errors.As(err, &overlapErr))
+	assert.Equal(t, []Conflict{
+		{First: 0, Second: 5, Days: []string{"Monday"}},
+		{First: 4, Second: 5, Days: []string{"Monday"}},
+	}, overlapErr.Conflicts)
+	assert.Equal(t, ir, a.List())
+	overlapping.AllowOverlap = OverlapLowest
+	ir, err = a.Patch(overlapping)
+	assert.Nil(t, err)
+	assert.Equal(t, OverlapLowest, ir.AllowOverlap)
+	// assert that the policy of the active rates is kept by later patches
+	ir, err = a.Patch(RatePatch{Remove: []string{"6"}})
+	assert.Nil(t, err)
+	assert.Equal(t, OverlapLowest, ir.AllowOverlap)
+	assert.Len(t, ir.Rates, 5)
+}
+
+func TestPatchKeepsPendingRates(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	effectiveFrom := time.Now().AddDate(0, 0, 30)
+	pending := IncomingRates{
+		Rates:         []RateDetail{{ID: "daily", Days: "mon,tues,wed,thurs,fri,sat,sun", Times: "0000-2400", TZ: "UTC", Price: 3000}},
+		EffectiveFrom: &effectiveFrom,
+	}
+	assert.Nil(t, a.Put(pending))
+
+	// assert that only the active rates are patched
+	_, err = a.Patch(RatePatch{Remove: []string{"daily"}})
+	assert.True(t, errors.Is(err, ErrRateNotFound))
+	ir, err := a.Patch(RatePatch{Remove: []string{"1"}})
+	assert.Nil(t, err)
+	assert.Len(t, ir.Rates, 4)
+	assert.Nil(t, ir.EffectiveFrom)
+	assert.Equal(t, []IncomingRates{pending}, a.Pending())
+}
+
+func TestPatchOverrides(t *testing.T) {
+	a := &API{}
+	christmas := Override{Name: "Christmas", Annual: "12-25", TZ: "UTC", Rates: []OverrideRate{}}
+	assert.Nil(t, a.Put(IncomingRates{
+		Rates:     []RateDetail{{Days: "mon", Times: "0900-1700", TZ: "UTC", Price: 1500}},
+		Overrides: []Override{christmas},
+	}))
+
+	// assert that the active overrides are kept and the patched overrides are added to them
+	newYear := Override{Name: "New Year", Annual: "01-01", TZ: "UTC", Rates: []OverrideRate{{Times: "0000-2400", Price: 3000}}}
+	ir, err := a.Patch(RatePatch{Remove: []string{"1"}, Overrides: []Override{newYear}})
+	assert.Nil(t, err)
+	assert.Equal(t, []RateDetail{}, ir.Rates)
+	assert.Equal(t, []Override{christmas, newYear}, ir.Overrides)
+	assert.Equal(t, []Override{christmas, newYear}, a.List().Overrides)
+
+	// assert that malformed overrides are rejected and the active overrides are left as they are
+	_, err = a.Patch(RatePatch{Overrides: []Override{{Name: "Someday", TZ: "UTC"}}})
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 2, validationErr.Errors[0].Index)
+	assert.Len(t, a.List().Overrides, 2)
+}
+
+func TestConcurrentPatches(t *testing.T) {
+	a := &API{}
+	assert.Nil(t, a.Put(IncomingRates{}))
+
+	// Every patch reads the rates that the previous patch put in place, so none of the added rates are lost
+	var wg sync.WaitGroup
+	for i := 0; i < 24; i++ {
+		wg.Add(1)
+		go func(hour int) {
+			defer wg.Done()
+			_, err := a.Patch(RatePatch{
+				Add: []RateDetail{{Days: "sat", Times: fmt.Sprintf("%02d00-%02d00", hour, hour+1), TZ: "UTC", Price: 100}},
+			})
+			assert.Nil(t, err)
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, a.List().Rates, 24)
+	assert.Len(t, a.Versions(), 1+24)
+}
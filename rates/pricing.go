@@ -0,0 +1,72 @@
+package rates
+
+import "time"
+
+// Pricing defines a rate that is charged by the duration of the time range instead of a flat price
+// The duration is billed in increments, with an optional price for the first hour and an optional daily maximum.
+type Pricing struct {
+	// IncrementMinutes is the number of minutes that are billed at a time. It defaults to 60.
+	IncrementMinutes int `json:"increment_minutes,omitempty" xml:"increment_minutes,omitempty"`
+	// PerIncrement is the price of each started increment
+	PerIncrement int `json:"per_increment" xml:"per_increment"`
+	// FirstHour is the price of the first hour of the time range. The increments are only charged
+	// after the first hour when it is set.
+	FirstHour int `json:"first_hour,omitempty" xml:"first_hour,omitempty"`
+	// DailyMax caps the price that is charged for each day in the timezone of the rate when it is set,
+	// however many segments of a time range the day is split into
+	DailyMax int `json:"daily_max,omitempty" xml:"daily_max,omitempty"`
+}
+
+// charge returns the price of parking for d, where offset is how long the car has already been parked
+// for before d starts. The offset makes sure that the first hour is only charged once for a time range
+// that is priced as several segments.
+func (p *Pricing) charge(offset, d time.Duration) int {
+	price := 0
+	if p.FirstHour > 0 && offset < time.Hour {
+		price += p.FirstHour
+		d -= time.Hour - offset
+	}
+	if d > 0 {
+		increment := time.Duration(p.IncrementMinutes) * time.Minute
+		if increment == 0 {
+			increment = time.Hour
+		}
+		// Every started increment is charged in full
+		increments := int((d + increment - 1) / increment)
+		price += increments * p.PerIncrement
+	}
+	if p.DailyMax > 0 && price > p.DailyMax {
+		price = p.DailyMax
+	}
+	return price
+}
+
+// dailyKey identifies the window of a rate on a single date in the timezone of the rate
+type dailyKey struct {
+	index    int
+	override string
+	start    int
+	date     string
+}
+
+// dailyCharges holds what the windows of rates with a daily maximum have already charged on each date
+// of a time range that is priced as several segments. A nil dailyCharges records nothing.
+type dailyCharges map[dailyKey]int
+
+// key returns the key of the window of r on the date of start in the timezone of r
+func (r DayRate) key(start time.Time) dailyKey {
+	return dailyKey{
+		index:    r.index,
+		override: r.override,
+		start:    r.startTime,
+		date:     start.In(r.loc).Format("2006-01-02"),
+	}
+}
+
+// add records that r charged price for a time range from start
+func (d dailyCharges) add(r DayRate, start time.Time, price int) {
+	if d == nil || r.pricing == nil || r.pricing.DailyMax == 0 {
+		return
+	}
+	d[r.key(start)] += price
+}
@@ -0,0 +1,181 @@
+package rates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPricingCharge(t *testing.T) {
+	testCases := []struct {
+		name    string
+		pricing Pricing
+		offset  time.Duration
+		d       time.Duration
+		price   int
+	}{
+		{
+			name:    "Hourly, started hours are charged in full",
+			pricing: Pricing{PerIncrement: 300},
+			d:       2*time.Hour + time.Minute,
+			price:   900,
+		},
+		{
+			name:    "Hourly, five minutes",
+			pricing: Pricing{PerIncrement: 300},
+			d:       5 * time.Minute,
+			price:   300,
+		},
+		{
+			name:    "Per increment",
+			pricing: Pricing{IncrementMinutes: 15, PerIncrement: 100},
+			d:       50 * time.Minute,
+			price:   400,
+		},
+		{
+			name:    "First hour only",
+			pricing: Pricing{FirstHour: 500, PerIncrement: 200},
+			d:       45 * time.Minute,
+			price:   500,
+		},
+		{
+			name:    "First hour and subsequent hours",
+			pricing: Pricing{FirstHour: 500, PerIncrement: 200},
+			d:       3*time.Hour + 30*time.Minute,
+			price:   1100,
+		},
+		{
+			name:    "First hour and subsequent increments",
+			pricing: Pricing{FirstHour: 500, IncrementMinutes: 30, PerIncrement: 150},
+			d:       2 * time.Hour,
+			price:   800,
+		},
+		{
+			name:    "First hour was charged in an earlier segment",
+			pricing: Pricing{FirstHour: 500, PerIncrement: 200},
+			offset:  3 * time.Hour,
+			d:       2 * time.Hour,
+			price:   400,
+		},
+		{
+			name:    "First hour was partly used in an earlier segment",
+			pricing: Pricing{FirstHour: 500, PerIncrement: 200},
+			offset:  30 * time.Minute,
+			d:       2 * time.Hour,
+			price:   900,
+		},
+		{
+			name:    "Daily maximum",
+			pricing: Pricing{FirstHour: 500, PerIncrement: 200, DailyMax: 2000},
+			d:       12 * time.Hour,
+			price:   2000,
+		},
+	}
+	for _, tt := range testCases {
+		assert.Equal(t, tt.price, tt.pricing.charge(tt.offset, tt.d), tt.name)
+	}
+}
+
+func TestGetRateWithPricing(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{
+			{
+				Days:    "fri,sat",
+				Times:   "0000-2400",
+				TZ:      "UTC",
+				Pricing: &Pricing{FirstHour: 500, PerIncrement: 200, DailyMax: 3000},
+			},
+			{Days: "sun", Times: "0000-2400", TZ: "UTC", Price: 1000},
+		},
+	})
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		name     string
+		p        ParkingTimesRequest
+		rate     int
+		segments []int
+	}{
+		{
+			name: "Five minutes",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 3, 10, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 3, 10, 5, 0, 0, time.UTC),
+			},
+			rate: 500,
+		},
+		{
+			name: "Sixteen hours are capped at the daily maximum",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 3, 6, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 3, 22, 0, 0, 0, time.UTC),
+			},
+			rate: 3000,
+		},
+		{
+			name: "Overnight, the first hour is only charged once",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 3, 22, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 4, 2, 0, 0, 0, time.UTC),
+				MultiDay:  true,
+			},
+			rate:     1100,
+			segments: []int{700, 400},
+		},
+		{
+			name: "Over the weekend, every day is capped and flat rates are mixed in",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 3, 8, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 5, 8, 0, 0, 0, time.UTC),
+				MultiDay:  true,
+			},
+			rate:     7000,
+			segments: []int{3000, 3000, 1000},
+		},
+	}
+	for _, tt := range testCases {
+		q, err := a.Quote(tt.p)
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, tt.rate, q.Rate, tt.name)
+		var segments []int
+		for _, s := range q.Segments {
+			segments = append(segments, s.Rate)
+		}
+		assert.Equal(t, tt.segments, segments, tt.name)
+	}
+}
+
+func TestGetRateWithPricingAcrossTimezones(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	// The rate of New York splits every day of Chicago into two segments at midnight in New York
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon,tues", Times: "0000-2400", TZ: "America/Chicago", Pricing: &Pricing{PerIncrement: 100, DailyMax: 500}},
+			{Days: "sun", Times: "0000-2400", TZ: "America/New_York", Price: 1000},
+		},
+	})
+	assert.Nil(t, err)
+
+	chicago, err := time.LoadLocation("America/Chicago")
+	assert.Nil(t, err)
+	for _, stitch := range []string{"", StitchSum, StitchMax} {
+		q, err := a.Quote(ParkingTimesRequest{
+			StartTime: time.Date(2020, 4, 6, 0, 0, 0, 0, chicago),
+			EndTime:   time.Date(2020, 4, 8, 0, 0, 0, 0, chicago),
+			MultiDay:  true,
+			Stitch:    stitch,
+		})
+		assert.Nil(t, err, stitch)
+		// assert that the daily maximum caps each day in Chicago once rather than each segment
+		assert.Equal(t, 1000, q.Rate, stitch)
+		var segments []int
+		for _, s := range q.Segments {
+			segments = append(segments, s.Rate)
+		}
+		assert.Equal(t, []int{500, 0, 500, 0}, segments, stitch)
+	}
+}
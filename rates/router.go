@@ -1,6 +1,9 @@
 package rates
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -10,34 +13,184 @@ import (
 )
 
 // PutResponse defines the response to updating with new rates
+// Conflicts are only present when the new rates were rejected for overlapping
+// Errors are only present when the new rates were rejected for being malformed
 type PutResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status    string       `json:"status" xml:"status"`
+	Message   string       `json:"message" xml:"message"`
+	Conflicts []Conflict   `json:"conflicts,omitempty" xml:"conflicts>conflict,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty" xml:"errors>error,omitempty"`
 }
 
 // RateResponse defines the response to getting a specific rate for a time span
+// Segments are only present when a multi day rate was requested
+// Windows are only present when windows were requested to be stitched together
 type RateResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
-	Rate    int    `json:"rate"`
+	Status   string    `json:"status" xml:"status"`
+	Message  string    `json:"message" xml:"message"`
+	Rate     int       `json:"rate" xml:"rate"`
+	Segments []Segment `json:"segments,omitempty" xml:"segments>segment,omitempty"`
+	Windows  []Window  `json:"windows,omitempty" xml:"windows>window,omitempty"`
+}
+
+// QuoteResponse defines the response to quoting a batch of time ranges
+// Quotes are in the same order as the time ranges in the request and each of them has its own status
+type QuoteResponse struct {
+	Status  string         `json:"status"`
+	Message string         `json:"message"`
+	Quotes  []RateResponse `json:"quotes"`
+}
+
+// SearchResponse defines the response to searching for the cheapest time ranges to park for
+// Candidates are ordered by their rate and then by their start time
+type SearchResponse struct {
+	Status     string      `json:"status"`
+	Message    string      `json:"message"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+// CalendarResponse defines the response to getting the rates of a week as a calendar
+type CalendarResponse struct {
+	Status  string        `json:"status"`
+	Message string        `json:"message"`
+	TZ      string        `json:"tz"`
+	Days    []CalendarDay `json:"days"`
+}
+
+// VersionsResponse defines the response to listing the versions of the rates
+type VersionsResponse struct {
+	Status   string    `json:"status"`
+	Message  string    `json:"message"`
+	Versions []Version `json:"versions"`
+}
+
+// VersionResponse defines the response to getting or rolling back to a specific version of the rates
+// Version is only present when the request was successful
+type VersionResponse struct {
+	Status  string   `json:"status"`
+	Message string   `json:"message"`
+	Version *Version `json:"version,omitempty"`
+}
+
+// PatchResponse defines the response to patching the rates
+// Rates holds the active rates after the patch and is only present when the patch was successful
+// Conflicts and Errors are only present when the patched rates were rejected, like in PutResponse
+type PatchResponse struct {
+	Status    string         `json:"status"`
+	Message   string         `json:"message"`
+	Rates     *IncomingRates `json:"rates,omitempty"`
+	Conflicts []Conflict     `json:"conflicts,omitempty"`
+	Errors    []FieldError   `json:"errors,omitempty"`
+}
+
+// DiffResponse defines the response to comparing new rates against the rates they would replace
+// Days lists the windows that would be added, removed or changed on each weekday
+// Conflicts and Errors are only present when the new rates would be rejected, like in PutResponse
+type DiffResponse struct {
+	Status    string         `json:"status"`
+	Message   string         `json:"message"`
+	Days      []DayDiff      `json:"days"`
+	Overrides []OverrideDiff `json:"overrides"`
+	Conflicts []Conflict     `json:"conflicts,omitempty"`
+	Errors    []FieldError   `json:"errors,omitempty"`
+}
+
+// AuditResponse defines the response to getting the audit log of the rates
+type AuditResponse struct {
+	Status  string        `json:"status"`
+	Message string        `json:"message"`
+	Records []AuditRecord `json:"records"`
+}
+
+// FacilityResponse defines the response to creating, getting, updating or deleting a facility
+// Facility is only present when the request was successful and the facility still exists
+type FacilityResponse struct {
+	Status   string    `json:"status"`
+	Message  string    `json:"message"`
+	Facility *Facility `json:"facility,omitempty"`
+}
+
+// FacilitiesResponse defines the response to listing the facilities
+type FacilitiesResponse struct {
+	Status     string     `json:"status"`
+	Message    string     `json:"message"`
+	Facilities []Facility `json:"facilities"`
+}
+
+// RouterOptions configures the optional endpoints and the authentication of a router.
+// The zero value registers only the endpoints of the Service and leaves every endpoint open.
+type RouterOptions struct {
+	// Facilities registers the endpoints to manage facilities and the rates of each facility
+	Facilities FacilityService
+	// Auth requires authentication on every endpoint but the health and metrics endpoints.
+	// Quote clients can only get quotes, while pricing admins can call every endpoint.
+	Auth *Auth
 }
 
 // NewRouter returns a router with the registered endpoints
 // It takes an interface as a parameter
 // This prevents the implementations from being tightly coupled to each other
 func NewRouter(s Service) *gin.Engine {
+	return NewRouterWithOptions(s, RouterOptions{})
+}
+
+// NewRouterWithOptions returns a router with the registered endpoints and the facility endpoints
+// and authentication of opts
+func NewRouterWithOptions(s Service, opts RouterOptions) *gin.Engine {
+	fs, auth := opts.Facilities, opts.Auth
+	quote := auth.Require(RoleQuote)
+	admin := auth.Require(RoleAdmin)
+
 	r := gin.Default()
-	r.Use(cors.Default())
+	// Browsers only send the credentials that the authentication reads when CORS allows their headers
+	config := cors.DefaultConfig()
+	config.AllowAllOrigins = true
+	config.AddAllowHeaders("Authorization", "X-API-Key")
+	r.Use(cors.New(config))
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status":  "ok",
 			"message": "rates app online",
 		})
 	})
-	r.PUT("/rates", PutRates(s))
-	r.GET("/rate", GetRate(s))
+	r.PUT("/rates", admin, PutRates(s))
+	r.PATCH("/rates", admin, PatchRates(s))
+	r.POST("/rates/holidays", admin, ImportHolidays(s))
+	r.POST("/rates/diff", admin, DiffRates(s))
+	r.GET("/rates", admin, ListRates(s))
+	r.GET("/rates/pending", admin, ListPendingRates(s))
+	r.GET("/rates/versions", admin, ListVersions(s))
+	r.GET("/rates/versions/:id", admin, GetVersion(s))
+	r.POST("/rates/versions/:id/rollback", admin, RollbackVersion(s))
+	r.GET("/rates/audit", admin, GetAudit(s))
+	r.GET("/rate", quote, GetRate(s))
+	r.POST("/rates/quote", quote, QuoteRates(s))
+	r.GET("/rates/search", quote, SearchRates(s))
+	r.GET("/rates/calendar", admin, GetCalendar(s))
 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	if fs != nil {
+		r.POST("/facilities", admin, CreateFacility(fs))
+		r.GET("/facilities", admin, ListFacilities(fs))
+		r.GET("/facilities/:id", admin, GetFacility(fs))
+		r.PUT("/facilities/:id", admin, UpdateFacility(fs))
+		r.DELETE("/facilities/:id", admin, DeleteFacility(fs))
+		// The rate endpoints of a facility are the same handlers as the global ones,
+		// called with the Service of the facility
+		r.PUT("/facilities/:id/rates", admin, ForFacility(fs, PutRates))
+		r.PATCH("/facilities/:id/rates", admin, ForFacility(fs, PatchRates))
+		r.POST("/facilities/:id/rates/holidays", admin, ForFacility(fs, ImportHolidays))
+		r.POST("/facilities/:id/rates/diff", admin, ForFacility(fs, DiffRates))
+		r.GET("/facilities/:id/rates", admin, ForFacility(fs, ListRates))
+		r.GET("/facilities/:id/rates/pending", admin, ForFacility(fs, ListPendingRates))
+		r.GET("/facilities/:id/rates/versions", admin, ForFacility(fs, ListVersions))
+		r.GET("/facilities/:id/rates/audit", admin, ForFacility(fs, GetAudit))
+		r.GET("/facilities/:id/rate", quote, ForFacility(fs, GetRate))
+		r.POST("/facilities/:id/rates/quote", quote, ForFacility(fs, QuoteRates))
+		r.GET("/facilities/:id/rates/search", quote, ForFacility(fs, SearchRates))
+		r.GET("/facilities/:id/rates/calendar", admin, ForFacility(fs, GetCalendar))
+	}
+
 	return r
 }
 
@@ -45,28 +198,63 @@ func NewRouter(s Service) *gin.Engine {
 func PutRates(s Service) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		tm := time.Now()
+		// The response is rendered in the format that the Accept header asks for
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
 		var ir IncomingRates
-		// Bind the json data to the struct
-		err := c.ShouldBindWith(&ir, binding.JSON)
+		// Bind the json or xml data to the struct
+		err := c.ShouldBindWith(&ir, bindingFor(c))
 		if err != nil {
 			recordPutBadRequest()
 			recordPutLatency(time.Since(tm))
 
-			c.JSON(400, PutResponse{
+			render(c, format, 400, PutResponse{
 				Status:  "error",
 				Message: err.Error(),
 			})
 			return
 		}
+		if ir.AllowOverlap, err = overlapPolicy(c, ir.AllowOverlap); err != nil {
+			recordPutBadRequest()
+			recordPutLatency(time.Since(tm))
+
+			render(c, format, 400, PutResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		// The authenticated caller is recorded in the audit log
+		ir.Caller, _ = CallerFrom(c)
 		// Call the Put function of the service to store the new rates and replace the older rates
 		err = s.Put(ir)
+		// If the rates are malformed or overlap, then return a 400 or a 422 with the reason
+		if r, ok := rejected(err); ok {
+			// record stats
+			if r.status == 422 {
+				recordPutConflict()
+			} else {
+				recordPutBadRequest()
+			}
+			recordPutLatency(time.Since(tm))
+
+			render(c, format, r.status, PutResponse{
+				Status:    "error",
+				Message:   r.message,
+				Errors:    r.errors,
+				Conflicts: r.conflicts,
+			})
+			return
+		}
 		if err != nil {
 			// record stats
 			recordPutFail()
 			recordPutLatency(time.Since(tm))
 
 			// If there was an error in Put, then return a 500 with an error response
-			c.JSON(500, PutResponse{
+			render(c, format, 500, PutResponse{
 				Status:  "error",
 				Message: err.Error(),
 			})
@@ -83,7 +271,7 @@ func PutRates(s Service) gin.HandlerFunc {
 		// The rates are already pre-seeded by seed_rates.json at the time of startup
 		// Any subsequent PUT calls with new rates, is updating the resource and not creating it.
 		// https://tools.ietf.org/html/rfc7231#section-4.3.4
-		c.JSON(200, PutResponse{
+		render(c, format, 200, PutResponse{
 			Status:  "success",
 			Message: "Successfully updated rates",
 		})
@@ -91,10 +279,51 @@ func PutRates(s Service) gin.HandlerFunc {
 	return gin.HandlerFunc(fn)
 }
 
-// GetRate is a wrapper around the Service Get function
+// overlapPolicy returns the overlap policy of the allow_overlap query param, which takes precedence
+// over policy, the policy of the body. It returns policy when the query param isn't given.
+func overlapPolicy(c *gin.Context, policy string) (string, error) {
+	query, ok := c.GetQuery("allow_overlap")
+	if !ok {
+		return policy, nil
+	}
+	if !validOverlapPolicy(query) {
+		return "", fmt.Errorf("unknown overlap policy: %s", query)
+	}
+	return query, nil
+}
+
+// rejection is the reason that rates were rejected, with the status code to respond with
+type rejection struct {
+	status    int
+	message   string
+	errors    []FieldError
+	conflicts []Conflict
+}
+
+// rejected returns the rejection of err when it rejects rates that are malformed, as a 400 with every
+// invalid field, or rates that overlap, as a 422 with every conflicting pair of rates.
+// ok is false for any other error.
+func rejected(err error) (r rejection, ok bool) {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return rejection{status: 400, message: validationErr.Error(), errors: validationErr.Errors}, true
+	}
+	var overlapErr *OverlapError
+	if errors.As(err, &overlapErr) {
+		return rejection{status: 422, message: overlapErr.Error(), conflicts: overlapErr.Conflicts}, true
+	}
+	return rejection{}, false
+}
+
+// GetRate is a wrapper around the Service Quote function
 func GetRate(s Service) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		tm := time.Now()
+		// The response is rendered in the format that the Accept header asks for
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
 		var p ParkingTimesRequest
 		// Bind the query params to the struct
 		err := c.Bind(&p)
@@ -104,15 +333,27 @@ func GetRate(s Service) gin.HandlerFunc {
 			recordGetRateBadRequest()
 			recordGetLatency(time.Since(tm))
 
-			c.JSON(400, RateResponse{
+			render(c, format, 400, RateResponse{
 				Status:  "error",
 				Message: err.Error(),
 				Rate:    0,
 			})
 			return
 		}
-		// Call the Get function of the service to attempt to retrieve the rate for the given time range
-		rate, err := s.Get(p)
+		if !validStitch(p.Stitch) {
+			// record stats
+			recordGetRateBadRequest()
+			recordGetLatency(time.Since(tm))
+
+			render(c, format, 400, RateResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("unknown stitch mode: %s", p.Stitch),
+				Rate:    0,
+			})
+			return
+		}
+		// Call the Quote function of the service to attempt to retrieve the rate for the given time range
+		q, err := s.Quote(p)
 		// If there was an error, return a 404 (not found) with a response containing the error
 		// When a rate is "unavailable", it will be sent as the value of Message
 		if err != nil {
@@ -120,7 +361,7 @@ func GetRate(s Service) gin.HandlerFunc {
 			recordGetRatetNotFound()
 			recordGetLatency(time.Since(tm))
 
-			c.JSON(404, RateResponse{
+			render(c, format, 404, RateResponse{
 				Status:  "error",
 				Message: err.Error(),
 				Rate:    0,
@@ -134,10 +375,591 @@ func GetRate(s Service) gin.HandlerFunc {
 		recordGetRateSuccess()
 		recordGetLatency(time.Since(tm))
 
-		c.JSON(200, RateResponse{
+		render(c, format, 200, RateResponse{
+			Status:   "success",
+			Message:  "success retrieving rate",
+			Rate:     q.Rate,
+			Segments: q.Segments,
+			Windows:  q.Windows,
+		})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// QuoteRates is a wrapper around the Service QuoteBatch function
+// A time range that can't be quoted doesn't fail the batch, its quote has an error status instead
+func QuoteRates(s Service) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		var ps []ParkingTimesRequest
+		// Bind the json array of time ranges to the slice
+		err := c.ShouldBindWith(&ps, binding.JSON)
+		if err != nil {
+			recordGetRateBadRequest()
+
+			c.JSON(400, QuoteResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if len(ps) > MaxBatchQuotes {
+			recordGetRateBadRequest()
+
+			c.JSON(400, QuoteResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("too many time ranges: %d, the maximum is %d", len(ps), MaxBatchQuotes),
+			})
+			return
+		}
+		quotes := make([]RateResponse, len(ps))
+		for i, result := range s.QuoteBatch(ps) {
+			if result.Err != nil {
+				// record stats
+				recordGetRatetNotFound()
+
+				quotes[i] = RateResponse{
+					Status:  "error",
+					Message: result.Err.Error(),
+				}
+				continue
+			}
+			// record stats
+			recordGetRateSuccess()
+
+			quotes[i] = RateResponse{
+				Status:   "success",
+				Message:  "success retrieving rate",
+				Rate:     result.Quote.Rate,
+				Segments: result.Quote.Segments,
+				Windows:  result.Quote.Windows,
+			}
+		}
+		c.JSON(200, QuoteResponse{
+			Status:  "success",
+			Message: "success retrieving rates",
+			Quotes:  quotes,
+		})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// SearchRates is a wrapper around the Service Search function
+// It takes the date as YYYY-MM-DD, the duration as a Go duration such as 2h or 90m
+// and the timezone of the date, which defaults to UTC
+func SearchRates(s Service) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		badRequest := func(message string) {
+			c.JSON(400, SearchResponse{
+				Status:  "error",
+				Message: message,
+			})
+		}
+		loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+		if err != nil {
+			badRequest(fmt.Sprintf("unknown timezone: %s", c.Query("tz")))
+			return
+		}
+		day, err := time.ParseInLocation("2006-01-02", c.Query("date"), loc)
+		if err != nil {
+			badRequest(fmt.Sprintf("date must be in the format YYYY-MM-DD: %s", c.Query("date")))
+			return
+		}
+		d, err := time.ParseDuration(c.Query("duration"))
+		if err != nil || d <= 0 {
+			badRequest(fmt.Sprintf("duration must be a positive duration such as 2h: %s", c.Query("duration")))
+			return
+		}
+		candidates, err := s.Search(day, d)
+		if err != nil {
+			badRequest(err.Error())
+			return
+		}
+		c.JSON(200, SearchResponse{
+			Status:     "success",
+			Message:    "success searching rates",
+			Candidates: candidates,
+		})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// GetCalendar is a wrapper around the Service Calendar function
+// It takes the timezone to render the calendar in, which defaults to UTC, and optionally
+// a date as YYYY-MM-DD in the week to render, which defaults to the current week
+func GetCalendar(s Service) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		tz := c.DefaultQuery("tz", "UTC")
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			c.JSON(400, CalendarResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("unknown timezone: %s", tz),
+			})
+			return
+		}
+		week := time.Now().In(loc)
+		if date, ok := c.GetQuery("date"); ok {
+			week, err = time.ParseInLocation("2006-01-02", date, loc)
+			if err != nil {
+				c.JSON(400, CalendarResponse{
+					Status:  "error",
+					Message: fmt.Sprintf("date must be in the format YYYY-MM-DD: %s", date),
+				})
+				return
+			}
+		}
+		c.JSON(200, CalendarResponse{
+			Status:  "success",
+			Message: "success retrieving calendar",
+			TZ:      loc.String(),
+			Days:    s.Calendar(week),
+		})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// PatchRates is a wrapper around the Service Patch function
+// It adds, updates and removes individual rates by their ids instead of replacing all of them
+func PatchRates(s Service) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		var p RatePatch
+		// Bind the json or xml data to the struct
+		if err := c.ShouldBindWith(&p, bindingFor(c)); err != nil {
+			c.JSON(400, PatchResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		var err error
+		if p.AllowOverlap, err = overlapPolicy(c, p.AllowOverlap); err != nil {
+			c.JSON(400, PatchResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		// The authenticated caller is recorded in the audit log
+		p.Caller, _ = CallerFrom(c)
+		patch(c, s, p, "Successfully patched rates")
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ImportHolidays is a wrapper around the Service Patch function
+// It adds every event of the iCalendar holiday list in the body as an override of the active rates.
+// The overrides are in the timezone of the tz query param and charge the price query param for the times
+// query param, which is the whole day by default. Without a price, parking is closed on the holidays.
+func ImportHolidays(s Service) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		rates := []OverrideRate{}
+		if price, ok := c.GetQuery("price"); ok {
+			p, err := strconv.Atoi(price)
+			if err != nil {
+				c.JSON(400, PatchResponse{
+					Status:  "error",
+					Message: fmt.Sprintf("price must be a whole number: %s", price),
+				})
+				return
+			}
+			rates = append(rates, OverrideRate{Times: c.DefaultQuery("times", "0000-2400"), Price: p})
+		}
+		overrides, err := ParseICS(c.Request.Body, c.Query("tz"), rates)
+		if err != nil {
+			c.JSON(400, PatchResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		caller, _ := CallerFrom(c)
+		patch(c, s, RatePatch{Overrides: overrides, Caller: caller}, "Successfully imported holidays")
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// patch applies p with the Service Patch function and responds with the patched rates or the reason
+// that they were rejected
+func patch(c *gin.Context, s Service, p RatePatch, message string) {
+	ir, err := s.Patch(p)
+	if errors.Is(err, ErrRateNotFound) {
+		c.JSON(404, PatchResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+	if r, ok := rejected(err); ok {
+		c.JSON(r.status, PatchResponse{
+			Status:    "error",
+			Message:   r.message,
+			Errors:    r.errors,
+			Conflicts: r.conflicts,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(500, PatchResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(200, PatchResponse{
+		Status:  "success",
+		Message: message,
+		Rates:   &ir,
+	})
+}
+
+// DiffRates is a wrapper around the Service Diff function
+// It takes the same body and query params as PutRates and responds with what PutRates would change, without
+// changing anything. Rates that PutRates would reject are rejected with the same status codes.
+func DiffRates(s Service) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		var ir IncomingRates
+		// Bind the json or xml data to the struct
+		if err := c.ShouldBindWith(&ir, bindingFor(c)); err != nil {
+			c.JSON(400, DiffResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		var err error
+		if ir.AllowOverlap, err = overlapPolicy(c, ir.AllowOverlap); err != nil {
+			c.JSON(400, DiffResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		diff, err := s.Diff(ir)
+		if r, ok := rejected(err); ok {
+			c.JSON(r.status, DiffResponse{
+				Status:    "error",
+				Message:   r.message,
+				Errors:    r.errors,
+				Conflicts: r.conflicts,
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(500, DiffResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(200, DiffResponse{
+			Status:    "success",
+			Message:   "success comparing rates",
+			Days:      diff.Days,
+			Overrides: diff.Overrides,
+		})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ListRates is a wrapper around the Service List function
+// It returns the active rates in the same shape that PUT /rates accepts them
+func ListRates(s Service) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		ir := s.List()
+
+		// record stats
+		recordListRatesSuccess()
+
+		c.JSON(200, ir)
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ListPendingRates is a wrapper around the Service Pending function
+// It returns the rates that are scheduled to take effect in the future, in the order they take effect
+func ListPendingRates(s Service) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.JSON(200, s.Pending())
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ListVersions is a wrapper around the Service Versions function
+func ListVersions(s Service) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.JSON(200, VersionsResponse{
+			Status:   "success",
+			Message:  "success retrieving versions",
+			Versions: s.Versions(),
+		})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// GetVersion is a wrapper around the Service Version function
+func GetVersion(s Service) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(400, VersionResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("invalid version id: %s", c.Param("id")),
+			})
+			return
+		}
+		v, err := s.Version(id)
+		if err != nil {
+			c.JSON(404, VersionResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(200, VersionResponse{
+			Status:  "success",
+			Message: "success retrieving version",
+			Version: &v,
+		})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// RollbackVersion is a wrapper around the Service Rollback function
+// The optional author query param is recorded with the version that the rollback creates
+func RollbackVersion(s Service) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(400, VersionResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("invalid version id: %s", c.Param("id")),
+			})
+			return
+		}
+		caller, _ := CallerFrom(c)
+		v, err := s.Rollback(id, c.Query("author"), caller)
+		if err == ErrVersionNotFound {
+			c.JSON(404, VersionResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(500, VersionResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		// The rollback creates a new version, so it returns a 201 with the new version
+		c.JSON(201, VersionResponse{
+			Status:  "success",
+			Message: fmt.Sprintf("Successfully rolled back to version %d", id),
+			Version: &v,
+		})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// GetAudit is a wrapper around the Service Audit function
+// The optional since query param is an RFC3339 time. Only the records from since onwards are returned.
+func GetAudit(s Service) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		var since time.Time
+		if v := c.Query("since"); v != "" {
+			var err error
+			since, err = time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(400, AuditResponse{
+					Status:  "error",
+					Message: fmt.Sprintf("since must be an RFC3339 time: %s", v),
+				})
+				return
+			}
+		}
+		records, err := s.Audit(since)
+		if err == ErrNoAuditLog {
+			c.JSON(404, AuditResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(500, AuditResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(200, AuditResponse{
+			Status:  "success",
+			Message: "success retrieving audit log",
+			Records: records,
+		})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ForFacility looks up the Service of the facility in the id path param and passes the request on
+// to the handler built from it. It returns a 404 if there is no such facility.
+func ForFacility(fs FacilityService, handler func(Service) gin.HandlerFunc) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		s, err := fs.Rates(c.Param("id"))
+		if err != nil {
+			c.JSON(404, FacilityResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		handler(s)(c)
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// CreateFacility is a wrapper around the FacilityService CreateFacility function
+func CreateFacility(fs FacilityService) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		var f Facility
+		if err := c.ShouldBindWith(&f, binding.JSON); err != nil {
+			c.JSON(400, FacilityResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		f, err := fs.CreateFacility(f)
+		if err == ErrFacilityExists {
+			c.JSON(409, FacilityResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		var facilityErr *FacilityError
+		if errors.As(err, &facilityErr) {
+			c.JSON(400, FacilityResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(500, FacilityResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(201, FacilityResponse{
+			Status:   "success",
+			Message:  "Successfully created facility",
+			Facility: &f,
+		})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ListFacilities is a wrapper around the FacilityService Facilities function
+func ListFacilities(fs FacilityService) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.JSON(200, FacilitiesResponse{
+			Status:     "success",
+			Message:    "success retrieving facilities",
+			Facilities: fs.Facilities(),
+		})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// GetFacility is a wrapper around the FacilityService Facility function
+func GetFacility(fs FacilityService) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		f, err := fs.Facility(c.Param("id"))
+		if err != nil {
+			c.JSON(404, FacilityResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(200, FacilityResponse{
+			Status:   "success",
+			Message:  "success retrieving facility",
+			Facility: &f,
+		})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// UpdateFacility is a wrapper around the FacilityService UpdateFacility function
+// The id of the facility is taken from the path, so it can't be changed
+func UpdateFacility(fs FacilityService) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		var f Facility
+		if err := c.ShouldBindWith(&f, binding.JSON); err != nil {
+			c.JSON(400, FacilityResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		f.ID = c.Param("id")
+		f, err := fs.UpdateFacility(f)
+		if err == ErrFacilityNotFound {
+			c.JSON(404, FacilityResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		var facilityErr *FacilityError
+		if errors.As(err, &facilityErr) {
+			c.JSON(400, FacilityResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(500, FacilityResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(200, FacilityResponse{
+			Status:   "success",
+			Message:  "Successfully updated facility",
+			Facility: &f,
+		})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// DeleteFacility is a wrapper around the FacilityService DeleteFacility function
+func DeleteFacility(fs FacilityService) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		err := fs.DeleteFacility(c.Param("id"))
+		if err == ErrFacilityNotFound {
+			c.JSON(404, FacilityResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(500, FacilityResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(200, FacilityResponse{
 			Status:  "success",
-			Message: "success retrieving rate",
-			Rate:    rate,
+			Message: "Successfully deleted facility",
 		})
 	}
 	return gin.HandlerFunc(fn)
@@ -3,12 +3,22 @@ package rates
 import (
 	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
+	"github.com/theblueskies/spothro/rates/ratespb"
 )
 
 func TestNewRouter(t *testing.T) {
@@ -31,6 +41,21 @@ func TestRouterHealth(t *testing.T) {
 	assert.Equal(t, "ok", b.Status)
 }
 
+func TestRouterCORSAllowsCredentialHeaders(t *testing.T) {
+	r := NewRouter(&mockService{})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("OPTIONS", "/rates", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	req.Header.Set("Access-Control-Request-Headers", "Authorization, X-API-Key")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 204, w.Code)
+	allowed := w.Header().Get("Access-Control-Allow-Headers")
+	assert.Contains(t, allowed, "Authorization")
+	assert.Contains(t, allowed, "X-Api-Key")
+}
+
 func TestPutRatesHandler(t *testing.T) {
 	rd := RateDetail{
 		Days:  "mon,tues,thurs",
@@ -92,6 +117,497 @@ func TestPutRatesHandler(t *testing.T) {
 	}
 }
 
+func TestPutRatesHandlerValidation(t *testing.T) {
+	fieldErrors := []FieldError{
+		{Index: 0, Field: "times", Message: `times must be in the format HHMM-HHMM or HH:MM:SS-HH:MM:SS: "0900"`},
+	}
+	m := &mockService{
+		err: &ValidationError{Errors: fieldErrors},
+	}
+	r := NewRouter(m)
+	jsonRates, err := json.Marshal(IncomingRates{
+		Rates: []RateDetail{{Days: "mon", Times: "0900", TZ: "America/Chicago", Price: 1500}},
+	})
+	assert.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/rates", bytes.NewBuffer(jsonRates))
+	r.ServeHTTP(w, req)
+
+	var b PutResponse
+	err = json.Unmarshal(w.Body.Bytes(), &b)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, PutResponse{
+		Status:  "error",
+		Message: "invalid rates: 1 field errors",
+		Errors:  fieldErrors,
+	}, b)
+}
+
+func TestPutRatesHandlerOverlap(t *testing.T) {
+	rd := RateDetail{
+		Days:  "mon,tues,thurs",
+		Times: "0900-2100",
+		TZ:    "America/Chicago",
+		Price: 1500,
+	}
+	newRates := IncomingRates{
+		Rates: []RateDetail{rd, rd},
+	}
+	conflicts := []Conflict{
+		{First: 0, Second: 1, Days: []string{"Monday", "Tuesday", "Thursday"}},
+	}
+	testCases := []struct {
+		name          string
+		m             *mockService
+		query         string
+		policy        string
+		outStatusCode int
+		outResponse   PutResponse
+	}{
+		{name: "put overlapping rates",
+			m: &mockService{
+				err: &OverlapError{Conflicts: conflicts},
+			},
+			query:         "",
+			policy:        "",
+			outStatusCode: 422,
+			outResponse: PutResponse{
+				Status:    "error",
+				Message:   "rates overlap: 1 conflicting pairs of rates",
+				Conflicts: conflicts,
+			},
+		},
+		{name: "put overlapping rates with policy",
+			m:             &mockService{},
+			query:         "?allow_overlap=lowest",
+			policy:        OverlapLowest,
+			outStatusCode: 200,
+			outResponse: PutResponse{
+				Status:  "success",
+				Message: "Successfully updated rates",
+			},
+		},
+		{name: "put overlapping rates with unknown policy",
+			m:             &mockService{},
+			query:         "?allow_overlap=newest",
+			policy:        "",
+			outStatusCode: 400,
+			outResponse: PutResponse{
+				Status:  "error",
+				Message: "unknown overlap policy: newest",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		r := NewRouter(tt.m)
+		jsonRates, err := json.Marshal(newRates)
+		assert.Nil(t, err)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/rates"+tt.query, bytes.NewBuffer(jsonRates))
+		r.ServeHTTP(w, req)
+
+		var b PutResponse
+		err = json.Unmarshal(w.Body.Bytes(), &b)
+		assert.Nil(t, err)
+
+		assert.Equal(t, tt.outStatusCode, w.Code, tt.name)
+		assert.Equal(t, tt.outResponse, b, tt.name)
+		assert.Equal(t, tt.policy, tt.m.lastRates.AllowOverlap, tt.name)
+	}
+}
+
+func TestPatchRatesHandler(t *testing.T) {
+	patched := IncomingRates{
+		Rates: []RateDetail{{ID: "1", Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1750}},
+	}
+	fieldErrors := []FieldError{
+		{Index: 1, Field: "times", Message: `times must be in the format HHMM-HHMM or HH:MM:SS-HH:MM:SS: "0900"`},
+	}
+	conflicts := []Conflict{
+		{First: 0, Second: 1, Days: []string{"Monday"}},
+	}
+	body := `{"update": [{"id": "1", "days": "mon", "times": "0900-2100", "tz": "America/Chicago", "price": 1750}], "remove": ["2"]}`
+	testCases := []struct {
+		name          string
+		m             *mockService
+		query         string
+		body          string
+		policy        string
+		outStatusCode int
+		outResponse   PatchResponse
+	}{
+		{
+			name:          "patch rates",
+			m:             &mockService{rates: patched},
+			body:          body,
+			outStatusCode: 200,
+			outResponse: PatchResponse{
+				Status:  "success",
+				Message: "Successfully patched rates",
+				Rates:   &patched,
+			},
+		},
+		{
+			name:          "patch rates with policy",
+			m:             &mockService{rates: patched},
+			query:         "?allow_overlap=lowest",
+			body:          body,
+			policy:        OverlapLowest,
+			outStatusCode: 200,
+			outResponse: PatchResponse{
+				Status:  "success",
+				Message: "Successfully patched rates",
+				Rates:   &patched,
+			},
+		},
+		{
+			name:          "patch rates with unknown policy",
+			m:             &mockService{},
+			query:         "?allow_overlap=newest",
+			body:          body,
+			outStatusCode: 400,
+			outResponse: PatchResponse{
+				Status:  "error",
+				Message: "unknown overlap policy: newest",
+			},
+		},
+		{
+			name:          "patch malformed body",
+			m:             &mockService{},
+			body:          `{"remove": "2"}`,
+			outStatusCode: 400,
+			outResponse: PatchResponse{
+				Status:  "error",
+				Message: "json: cannot unmarshal string into Go struct field RatePatch.remove of type []string",
+			},
+		},
+		{
+			name:          "patch unknown rate",
+			m:             &mockService{err: fmt.Errorf("%w: %q", ErrRateNotFound, "2")},
+			body:          body,
+			outStatusCode: 404,
+			outResponse: PatchResponse{
+				Status:  "error",
+				Message: `rate not found: "2"`,
+			},
+		},
+		{
+			name:          "patch malformed rates",
+			m:             &mockService{err: &ValidationError{Errors: fieldErrors}},
+			body:          body,
+			outStatusCode: 400,
+			outResponse: PatchResponse{
+				Status:  "error",
+				Message: "invalid rates: 1 field errors",
+				Errors:  fieldErrors,
+			},
+		},
+		{
+			name:          "patch overlapping rates",
+			m:             &mockService{err: &OverlapError{Conflicts: conflicts}},
+			body:          body,
+			outStatusCode: 422,
+			outResponse: PatchResponse{
+				Status:    "error",
+				Message:   "rates overlap: 1 conflicting pairs of rates",
+				Conflicts: conflicts,
+			},
+		},
+		{
+			name:          "patch fails",
+			m:             &mockService{err: errors.New("Simulating error patching rates")},
+			body:          body,
+			outStatusCode: 500,
+			outResponse: PatchResponse{
+				Status:  "error",
+				Message: "Simulating error patching rates",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		r := NewRouter(tt.m)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PATCH", "/rates"+tt.query, strings.NewReader(tt.body))
+		r.ServeHTTP(w, req)
+
+		var b PatchResponse
+		err := json.Unmarshal(w.Body.Bytes(), &b)
+		assert.Nil(t, err, tt.name)
+
+		assert.Equal(t, tt.outStatusCode, w.Code, tt.name)
+		assert.Equal(t, tt.outResponse, b, tt.name)
+		assert.Equal(t, tt.policy, tt.m.lastPatch.AllowOverlap, tt.name)
+		if tt.outStatusCode != 400 {
+			assert.Equal(t, []string{"2"}, tt.m.lastPatch.Remove, tt.name)
+		}
+	}
+}
+
+func TestImportHolidaysHandler(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;VALUE=DATE:20201225\nRRULE:FREQ=YEARLY\nSUMMARY:Christmas Day\nEND:VEVENT\nEND:VCALENDAR"
+	patched := IncomingRates{
+		Rates:     []RateDetail{},
+		Overrides: []Override{{Name: "Christmas Day", Annual: "12-25", TZ: "UTC", Rates: []OverrideRate{}}},
+	}
+	testCases := []struct {
+		name          string
+		m             *mockService
+		query         string
+		body          string
+		overrides     []Override
+		outStatusCode int
+		outResponse   PatchResponse
+	}{
+		{
+			name:  "import holidays with a price",
+			m:     &mockService{rates: patched},
+			query: "?tz=America/Chicago&price=3000&times=0600-2200",
+			body:  ics,
+			overrides: []Override{
+				{Name: "Christmas Day", Annual: "12-25", TZ: "America/Chicago", Rates: []OverrideRate{{Times: "0600-2200", Price: 3000}}},
+			},
+			outStatusCode: 200,
+			outResponse: PatchResponse{
+				Status:  "success",
+				Message: "Successfully imported holidays",
+				Rates:   &patched,
+			},
+		},
+		{
+			name:  "import holidays for the whole day",
+			m:     &mockService{rates: patched},
+			query: "?tz=UTC&price=3000",
+			body:  ics,
+			overrides: []Override{
+				{Name: "Christmas Day", Annual: "12-25", TZ: "UTC", Rates: []OverrideRate{{Times: "0000-2400", Price: 3000}}},
+			},
+			outStatusCode: 200,
+			outResponse: PatchResponse{
+				Status:  "success",
+				Message: "Successfully imported holidays",
+				Rates:   &patched,
+			},
+		},
+		{
+			name:  "import holidays that are closed",
+			m:     &mockService{rates: patched},
+			query: "?tz=UTC",
+			body:  ics,
+			overrides: []Override{
+				{Name: "Christmas Day", Annual: "12-25", TZ: "UTC", Rates: []OverrideRate{}},
+			},
+			outStatusCode: 200,
+			outResponse: PatchResponse{
+				Status:  "success",
+				Message: "Successfully imported holidays",
+				Rates:   &patched,
+			},
+		},
+		{
+			name:          "import holidays with a malformed price",
+			m:             &mockService{},
+			query:         "?tz=UTC&price=free",
+			body:          ics,
+			outStatusCode: 400,
+			outResponse: PatchResponse{
+				Status:  "error",
+				Message: "price must be a whole number: free",
+			},
+		},
+		{
+			name:          "import a malformed holiday list",
+			m:             &mockService{},
+			query:         "?tz=UTC",
+			body:          "BEGIN:VEVENT\nSUMMARY:Someday\nEND:VEVENT",
+			outStatusCode: 400,
+			outResponse: PatchResponse{
+				Status:  "error",
+				Message: `event date must be in the format YYYYMMDD: ""`,
+			},
+		},
+		{
+			name: "import holidays without a timezone",
+			m: &mockService{err: &ValidationError{Errors: []FieldError{
+				{Index: 0, Field: "overrides.tz", Message: "tz is required"},
+			}}},
+			body: ics,
+			overrides: []Override{
+				{Name: "Christmas Day", Annual: "12-25", Rates: []OverrideRate{}},
+			},
+			outStatusCode: 400,
+			outResponse: PatchResponse{
+				Status:  "error",
+				Message: "invalid rates: 1 field errors",
+				Errors:  []FieldError{{Index: 0, Field: "overrides.tz", Message: "tz is required"}},
+			},
+		},
+		{
+			name:  "import holidays fails",
+			m:     &mockService{err: errors.New("Simulating error importing holidays")},
+			query: "?tz=UTC",
+			body:  ics,
+			overrides: []Override{
+				{Name: "Christmas Day", Annual: "12-25", TZ: "UTC", Rates: []OverrideRate{}},
+			},
+			outStatusCode: 500,
+			outResponse: PatchResponse{
+				Status:  "error",
+				Message: "Simulating error importing holidays",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		r := NewRouter(tt.m)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/rates/holidays"+tt.query, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "text/calendar")
+		r.ServeHTTP(w, req)
+
+		var b PatchResponse
+		err := json.Unmarshal(w.Body.Bytes(), &b)
+		assert.Nil(t, err, tt.name)
+
+		assert.Equal(t, tt.outStatusCode, w.Code, tt.name)
+		assert.Equal(t, tt.outResponse, b, tt.name)
+		assert.Equal(t, tt.overrides, tt.m.lastPatch.Overrides, tt.name)
+	}
+}
+
+func TestDiffRatesHandler(t *testing.T) {
+	newRates := IncomingRates{
+		Rates: []RateDetail{{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1750}},
+	}
+	days := []DayDiff{{
+		Day: "Monday",
+		Changed: []Change{{
+			Before: RateDetail{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+			After:  RateDetail{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1750},
+		}},
+	}}
+	christmas := Override{Name: "Christmas", Annual: "12-25", TZ: "America/Chicago", Rates: []OverrideRate{}}
+	overrides := []OverrideDiff{{Name: "Christmas", Dates: "every 12-25", After: &christmas}}
+	fieldErrors := []FieldError{
+		{Index: 0, Field: "times", Message: `times must be in the format HHMM-HHMM or HH:MM:SS-HH:MM:SS: "0900"`},
+	}
+	conflicts := []Conflict{
+		{First: 0, Second: 1, Days: []string{"Monday"}},
+	}
+	testCases := []struct {
+		name          string
+		m             *mockService
+		query         string
+		body          string
+		policy        string
+		outStatusCode int
+		outResponse   DiffResponse
+	}{
+		{
+			name:          "diff rates",
+			m:             &mockService{diff: RateDiff{Days: days, Overrides: overrides}},
+			outStatusCode: 200,
+			outResponse: DiffResponse{
+				Status:    "success",
+				Message:   "success comparing rates",
+				Days:      days,
+				Overrides: overrides,
+			},
+		},
+		{
+			name:          "diff rates with policy",
+			m:             &mockService{diff: RateDiff{Days: []DayDiff{}, Overrides: []OverrideDiff{}}},
+			query:         "?allow_overlap=highest",
+			policy:        OverlapHighest,
+			outStatusCode: 200,
+			outResponse: DiffResponse{
+				Status:    "success",
+				Message:   "success comparing rates",
+				Days:      []DayDiff{},
+				Overrides: []OverrideDiff{},
+			},
+		},
+		{
+			name:          "diff rates with unknown policy",
+			m:             &mockService{},
+			query:         "?allow_overlap=newest",
+			outStatusCode: 400,
+			outResponse: DiffResponse{
+				Status:  "error",
+				Message: "unknown overlap policy: newest",
+			},
+		},
+		{
+			name:          "diff malformed body",
+			m:             &mockService{},
+			body:          `{"rates": "mon"}`,
+			outStatusCode: 400,
+			outResponse: DiffResponse{
+				Status:  "error",
+				Message: "json: cannot unmarshal string into Go struct field IncomingRates.rates of type []rates.RateDetail",
+			},
+		},
+		{
+			name:          "diff malformed rates",
+			m:             &mockService{err: &ValidationError{Errors: fieldErrors}},
+			outStatusCode: 400,
+			outResponse: DiffResponse{
+				Status:  "error",
+				Message: "invalid rates: 1 field errors",
+				Errors:  fieldErrors,
+			},
+		},
+		{
+			name:          "diff overlapping rates",
+			m:             &mockService{err: &OverlapError{Conflicts: conflicts}},
+			outStatusCode: 422,
+			outResponse: DiffResponse{
+				Status:    "error",
+				Message:   "rates overlap: 1 conflicting pairs of rates",
+				Conflicts: conflicts,
+			},
+		},
+		{
+			name:          "diff fails",
+			m:             &mockService{err: errors.New("Simulating error comparing rates")},
+			outStatusCode: 500,
+			outResponse: DiffResponse{
+				Status:  "error",
+				Message: "Simulating error comparing rates",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		r := NewRouter(tt.m)
+		body := tt.body
+		if body == "" {
+			jsonRates, err := json.Marshal(newRates)
+			assert.Nil(t, err)
+			body = string(jsonRates)
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/rates/diff"+tt.query, strings.NewReader(body))
+		r.ServeHTTP(w, req)
+
+		var b DiffResponse
+		err := json.Unmarshal(w.Body.Bytes(), &b)
+		assert.Nil(t, err, tt.name)
+
+		assert.Equal(t, tt.outStatusCode, w.Code, tt.name)
+		assert.Equal(t, tt.outResponse, b, tt.name)
+		assert.Equal(t, tt.policy, tt.m.lastRates.AllowOverlap, tt.name)
+		// Nothing is ever put
+		assert.Equal(t, 0, tt.m.putCallCount, tt.name)
+	}
+}
+
 func TestGetRateHandler(t *testing.T) {
 	testCases := []struct {
 		name          string
@@ -154,26 +670,1139 @@ func TestGetRateHandler(t *testing.T) {
 	}
 }
 
-type mockService struct {
-	Service
-	putCallCount int
-	getCallCount int
-	rate         int
-	err          error
-}
-
-func (m *mockService) Put(ir IncomingRates) error {
-	if m.err != nil {
-		return m.err
+func TestGetMultiDayRateHandler(t *testing.T) {
+	segments := []Segment{
+		{
+			StartTime: time.Date(2015, 7, 4, 20, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2015, 7, 5, 0, 0, 0, 0, time.UTC),
+			Rate:      2000,
+		},
+		{
+			StartTime: time.Date(2015, 7, 5, 0, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2015, 7, 5, 10, 0, 0, 0, time.UTC),
+			Rate:      1000,
+		},
 	}
-	m.putCallCount++
-	return nil
+	m := &mockService{
+		rate:     3000,
+		segments: segments,
+	}
+	r := NewRouter(m)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/rate", nil)
+	q := req.URL.Query()
+	q.Add("start_time", "2015-07-04T20:00:00Z")
+	q.Add("end_time", "2015-07-05T10:00:00Z")
+	q.Add("multi_day", "true")
+	req.URL.RawQuery = q.Encode()
+	r.ServeHTTP(w, req)
+
+	var b RateResponse
+	err := json.Unmarshal(w.Body.Bytes(), &b)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 200, w.Code)
+	assert.True(t, m.lastRequest.MultiDay)
+	assert.Equal(t, RateResponse{
+		Status:   "success",
+		Message:  "success retrieving rate",
+		Rate:     3000,
+		Segments: segments,
+	}, b)
 }
 
-func (m *mockService) Get(p ParkingTimesRequest) (rate int, err error) {
-	if m.err != nil {
+func TestGetStitchedRateHandler(t *testing.T) {
+	windows := []Window{
+		{
+			Index:     0,
+			Day:       "Monday",
+			Times:     "0600-0900",
+			TZ:        "UTC",
+			StartTime: time.Date(2015, 7, 6, 8, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2015, 7, 6, 9, 0, 0, 0, time.UTC),
+			Rate:      1000,
+		},
+		{
+			Index:     1,
+			Day:       "Monday",
+			Times:     "0900-2100",
+			TZ:        "UTC",
+			StartTime: time.Date(2015, 7, 6, 9, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2015, 7, 6, 10, 0, 0, 0, time.UTC),
+			Rate:      1500,
+		},
+	}
+	testCases := []struct {
+		name          string
+		stitch        string
+		getCallCount  int
+		outStatusCode int
+		outResponse   RateResponse
+	}{
+		{
+			name:          "stitched windows",
+			stitch:        StitchSum,
+			getCallCount:  1,
+			outStatusCode: 200,
+			outResponse: RateResponse{
+				Status:  "success",
+				Message: "success retrieving rate",
+				Rate:    2500,
+				Windows: windows,
+			},
+		},
+		{
+			name:          "unknown stitch mode",
+			stitch:        "min",
+			getCallCount:  0,
+			outStatusCode: 400,
+			outResponse: RateResponse{
+				Status:  "error",
+				Message: "unknown stitch mode: min",
+			},
+		},
+	}
+	for _, tt := range testCases {
+		m := &mockService{
+			rate:    2500,
+			windows: windows,
+		}
+		r := NewRouter(m)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/rate", nil)
+		q := req.URL.Query()
+		q.Add("start_time", "2015-07-06T08:00:00Z")
+		q.Add("end_time", "2015-07-06T10:00:00Z")
+		q.Add("stitch", tt.stitch)
+		req.URL.RawQuery = q.Encode()
+		r.ServeHTTP(w, req)
+
+		var b RateResponse
+		err := json.Unmarshal(w.Body.Bytes(), &b)
+		assert.Nil(t, err, tt.name)
+
+		assert.Equal(t, tt.outStatusCode, w.Code, tt.name)
+		assert.Equal(t, tt.outResponse, b, tt.name)
+		assert.Equal(t, tt.getCallCount, m.getCallCount, tt.name)
+	}
+}
+
+func TestConcurrentGetAndPutRates(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	r := NewRouter(a)
+
+	// Every put replaces the whole week with one of two prices, so a reader always gets one of them
+	// and never a mix of the rates that were being replaced and the rates that replaced them
+	prices := []int{1500, 1750}
+	body := func(price int) string {
+		return fmt.Sprintf(`{"rates": [{"days": "mon,tues,wed,thurs,fri,sat,sun", "times": "0000-2400", "tz": "UTC", "price": %d}]}`, price)
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/rates", strings.NewReader(body(prices[0])))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest("PUT", "/rates", strings.NewReader(body(prices[(i+j)%2])))
+				r.ServeHTTP(w, req)
+				assert.Equal(t, 200, w.Code)
+			}
+		}(i)
+	}
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest("GET", "/rate?start_time=2020-04-03T14:30:00Z&end_time=2020-04-03T19:30:00Z", nil)
+				r.ServeHTTP(w, req)
+				var b RateResponse
+				assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &b))
+				assert.Equal(t, 200, w.Code)
+				assert.Contains(t, prices, b.Rate)
+
+				w = httptest.NewRecorder()
+				req, _ = http.NewRequest("GET", "/rates", nil)
+				r.ServeHTTP(w, req)
+				var ir IncomingRates
+				assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &ir))
+				assert.Len(t, ir.Rates, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, a.Versions(), 2+4*50)
+}
+
+func TestQuoteRatesHandler(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	testCases := []struct {
+		name          string
+		s             Service
+		body          string
+		outStatusCode int
+		outResponse   QuoteResponse
+	}{
+		{
+			name: "quote batch",
+			s:    a,
+			body: `[
+				{"start_time": "2020-04-03T14:30:00Z", "end_time": "2020-04-03T19:30:00Z"},
+				{"start_time": "2020-04-04T07:00:00Z", "end_time": "2020-04-04T20:00:00Z"},
+				{"start_time": "2020-04-01T12:00:00Z", "end_time": "2020-04-01T13:00:00Z"}
+			]`,
+			outStatusCode: 200,
+			outResponse: QuoteResponse{
+				Status:  "success",
+				Message: "success retrieving rates",
+				Quotes: []RateResponse{
+					{Status: "success", Message: "success retrieving rate", Rate: 2000},
+					{Status: "error", Message: "unavailable"},
+					{Status: "success", Message: "success retrieving rate", Rate: 1750},
+				},
+			},
+		},
+		{
+			name:          "quote empty batch",
+			s:             a,
+			body:          `[]`,
+			outStatusCode: 200,
+			outResponse: QuoteResponse{
+				Status:  "success",
+				Message: "success retrieving rates",
+				Quotes:  []RateResponse{},
+			},
+		},
+		{
+			name:          "quote batch with every time range failing",
+			s:             &mockService{err: errors.New("unavailable")},
+			body:          `[{"start_time": "2020-04-03T14:30:00Z", "end_time": "2020-04-03T19:30:00Z"}]`,
+			outStatusCode: 200,
+			outResponse: QuoteResponse{
+				Status:  "success",
+				Message: "success retrieving rates",
+				Quotes:  []RateResponse{{Status: "error", Message: "unavailable"}},
+			},
+		},
+		{
+			name:          "quote batch that is not an array",
+			s:             a,
+			body:          `{"start_time": "2020-04-03T14:30:00Z", "end_time": "2020-04-03T19:30:00Z"}`,
+			outStatusCode: 400,
+			outResponse: QuoteResponse{
+				Status:  "error",
+				Message: "json: cannot unmarshal object into Go value of type []rates.ParkingTimesRequest",
+			},
+		},
+		{
+			name:          "quote batch that is too large",
+			s:             a,
+			body:          "[" + strings.Repeat(`{},`, MaxBatchQuotes) + "{}]",
+			outStatusCode: 400,
+			outResponse: QuoteResponse{
+				Status:  "error",
+				Message: "too many time ranges: 1001, the maximum is 1000",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		r := NewRouter(tt.s)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/rates/quote", bytes.NewBufferString(tt.body))
+		r.ServeHTTP(w, req)
+
+		var b QuoteResponse
+		err := json.Unmarshal(w.Body.Bytes(), &b)
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, tt.outStatusCode, w.Code, tt.name)
+		assert.Equal(t, tt.outResponse, b, tt.name)
+	}
+}
+
+func TestSearchRatesHandler(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	assert.Nil(t, err)
+	candidates := []Candidate{
+		{
+			StartTime: time.Date(2020, 4, 1, 1, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2020, 4, 1, 3, 0, 0, 0, time.UTC),
+			Rate:      1000,
+		},
+	}
+	testCases := []struct {
+		name          string
+		m             *mockService
+		query         string
+		outStatusCode int
+		outResponse   SearchResponse
+		day           time.Time
+		duration      time.Duration
+	}{
+		{
+			name:          "search",
+			m:             &mockService{candidates: candidates},
+			query:         "?date=2020-04-01&duration=2h&tz=America/Chicago",
+			outStatusCode: 200,
+			outResponse: SearchResponse{
+				Status:     "success",
+				Message:    "success searching rates",
+				Candidates: candidates,
+			},
+			day:      time.Date(2020, 4, 1, 0, 0, 0, 0, chicago),
+			duration: 2 * time.Hour,
+		},
+		{
+			name:          "search defaults to UTC",
+			m:             &mockService{candidates: []Candidate{}},
+			query:         "?date=2020-04-01&duration=90m",
+			outStatusCode: 200,
+			outResponse: SearchResponse{
+				Status:     "success",
+				Message:    "success searching rates",
+				Candidates: []Candidate{},
+			},
+			day:      time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC),
+			duration: 90 * time.Minute,
+		},
+		{
+			name:          "search with unknown timezone",
+			m:             &mockService{},
+			query:         "?date=2020-04-01&duration=2h&tz=Mars/Olympus_Mons",
+			outStatusCode: 400,
+			outResponse: SearchResponse{
+				Status:  "error",
+				Message: "unknown timezone: Mars/Olympus_Mons",
+			},
+		},
+		{
+			name:          "search with invalid date",
+			m:             &mockService{},
+			query:         "?date=04/01/2020&duration=2h",
+			outStatusCode: 400,
+			outResponse: SearchResponse{
+				Status:  "error",
+				Message: "date must be in the format YYYY-MM-DD: 04/01/2020",
+			},
+		},
+		{
+			name:          "search with invalid duration",
+			m:             &mockService{},
+			query:         "?date=2020-04-01&duration=-2h",
+			outStatusCode: 400,
+			outResponse: SearchResponse{
+				Status:  "error",
+				Message: "duration must be a positive duration such as 2h: -2h",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		r := NewRouter(tt.m)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/rates/search"+tt.query, nil)
+		r.ServeHTTP(w, req)
+
+		var b SearchResponse
+		err := json.Unmarshal(w.Body.Bytes(), &b)
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, tt.outStatusCode, w.Code, tt.name)
+		assert.Equal(t, tt.outResponse, b, tt.name)
+		assert.True(t, tt.day.Equal(tt.m.lastDay), tt.name)
+		assert.Equal(t, tt.duration, tt.m.lastDuration, tt.name)
+	}
+}
+
+func TestGetCalendarHandler(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	assert.Nil(t, err)
+	calendar := []CalendarDay{
+		{
+			Day:       "Monday",
+			Date:      "2020-03-30",
+			Intervals: []CalendarInterval{{Start: "09:00", End: "21:00", Index: 0, Price: 1500}},
+			Gaps:      []Gap{{Start: "00:00", End: "09:00"}, {Start: "21:00", End: "24:00"}},
+		},
+	}
+	testCases := []struct {
+		name          string
+		query         string
+		outStatusCode int
+		outResponse   CalendarResponse
+		week          time.Time
+	}{
+		{
+			name:          "calendar of a week",
+			query:         "?tz=America/Chicago&date=2020-04-01",
+			outStatusCode: 200,
+			outResponse: CalendarResponse{
+				Status:  "success",
+				Message: "success retrieving calendar",
+				TZ:      "America/Chicago",
+				Days:    calendar,
+			},
+			week: time.Date(2020, 4, 1, 0, 0, 0, 0, chicago),
+		},
+		{
+			name:          "calendar with unknown timezone",
+			query:         "?tz=Mars/Olympus_Mons",
+			outStatusCode: 400,
+			outResponse: CalendarResponse{
+				Status:  "error",
+				Message: "unknown timezone: Mars/Olympus_Mons",
+			},
+		},
+		{
+			name:          "calendar with invalid date",
+			query:         "?date=yesterday",
+			outStatusCode: 400,
+			outResponse: CalendarResponse{
+				Status:  "error",
+				Message: "date must be in the format YYYY-MM-DD: yesterday",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		m := &mockService{calendar: calendar}
+		r := NewRouter(m)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/rates/calendar"+tt.query, nil)
+		r.ServeHTTP(w, req)
+
+		var b CalendarResponse
+		err := json.Unmarshal(w.Body.Bytes(), &b)
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, tt.outStatusCode, w.Code, tt.name)
+		assert.Equal(t, tt.outResponse, b, tt.name)
+		assert.True(t, tt.week.Equal(m.lastDay), tt.name)
+	}
+
+	// The calendar defaults to the current week in UTC
+	m := &mockService{calendar: calendar}
+	r := NewRouter(m)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/rates/calendar", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, time.UTC, m.lastDay.Location())
+	assert.WithinDuration(t, time.Now(), m.lastDay, time.Minute)
+}
+
+func TestContentNegotiation(t *testing.T) {
+	query := "/rate?start_time=2015-07-01T07:00:00-05:00&end_time=2015-07-01T12:00:00-05:00"
+	m := &mockService{rate: 1750}
+	r := NewRouter(m)
+
+	// XML
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", query, nil)
+	req.Header.Set("Accept", "application/xml")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	var xmlResp RateResponse
+	assert.Nil(t, xml.Unmarshal(w.Body.Bytes(), &xmlResp))
+	assert.Equal(t, RateResponse{Status: "success", Message: "success retrieving rate", Rate: 1750}, xmlResp)
+
+	// Protobuf
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", query, nil)
+	req.Header.Set("Accept", "application/x-protobuf")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+	var pbResp ratespb.RateResponse
+	assert.Nil(t, proto.Unmarshal(w.Body.Bytes(), &pbResp))
+	assert.Equal(t, int64(1750), pbResp.Rate)
+	assert.Equal(t, "success", pbResp.Status)
+
+	// The first supported type in the Accept header is used
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", query, nil)
+	req.Header.Set("Accept", "text/html, text/xml;q=0.9, */*;q=0.8")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+
+	// Unsupported types are rejected with a 406 without calling the service
+	getCallCount := m.getCallCount
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", query, nil)
+	req.Header.Set("Accept", "text/html")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 406, w.Code)
+	assert.Equal(t, getCallCount, m.getCallCount)
+	var b PutResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &b))
+	assert.Equal(t, PutResponse{Status: "error", Message: "unsupported accept header: text/html"}, b)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/rates", bytes.NewBufferString(`{"rates": []}`))
+	req.Header.Set("Accept", "text/csv")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 406, w.Code)
+	assert.Equal(t, 0, m.putCallCount)
+}
+
+func TestPutRatesHandlerXML(t *testing.T) {
+	m := &mockService{}
+	r := NewRouter(m)
+	body := `<rates>
+		<rate>
+			<days>mon,tues,thurs</days>
+			<times>0900-2100</times>
+			<tz>America/Chicago</tz>
+			<price>1500</price>
+		</rate>
+		<rate>
+			<days>sun</days>
+			<times>0000-2400</times>
+			<tz>UTC</tz>
+			<pricing>
+				<increment_minutes>30</increment_minutes>
+				<per_increment>150</per_increment>
+			</pricing>
+		</rate>
+		<author>pricing</author>
+	</rates>`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/rates", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Accept", "application/xml")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon,tues,thurs", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+			{Days: "sun", Times: "0000-2400", TZ: "UTC", Pricing: &Pricing{IncrementMinutes: 30, PerIncrement: 150}},
+		},
+		Author: "pricing",
+	}, m.lastRates)
+	var b PutResponse
+	assert.Nil(t, xml.Unmarshal(w.Body.Bytes(), &b))
+	assert.Equal(t, PutResponse{Status: "success", Message: "Successfully updated rates"}, b)
+
+	// The errors of rejected rates are rendered in the negotiated format too
+	m = &mockService{err: &ValidationError{Errors: []FieldError{{Index: 0, Field: "times", Message: "bad times"}}}}
+	r = NewRouter(m)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/rates", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Accept", "application/x-protobuf")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+	var pbResp ratespb.PutResponse
+	assert.Nil(t, proto.Unmarshal(w.Body.Bytes(), &pbResp))
+	assert.Equal(t, "error", pbResp.Status)
+	assert.Equal(t, "times", pbResp.Errors[0].Field)
+
+	// Malformed XML is a bad request
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/rates", bytes.NewBufferString("<rates><rate>"))
+	req.Header.Set("Content-Type", "text/xml")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestListRatesHandler(t *testing.T) {
+	rd := RateDetail{
+		Days:  "mon,tues,thurs",
+		Times: "0900-2100",
+		TZ:    "America/Chicago",
+		Price: 1500,
+	}
+	m := &mockService{
+		rates: IncomingRates{
+			Rates: []RateDetail{rd},
+		},
+	}
+	r := NewRouter(m)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/rates", nil)
+	r.ServeHTTP(w, req)
+
+	var b IncomingRates
+	err := json.Unmarshal(w.Body.Bytes(), &b)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, m.rates, b)
+}
+
+func TestListPendingRatesHandler(t *testing.T) {
+	effectiveFrom := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := &mockService{
+		pending: []IncomingRates{
+			{
+				Rates:         []RateDetail{{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 2000}},
+				EffectiveFrom: &effectiveFrom,
+			},
+		},
+	}
+	r := NewRouter(m)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/rates/pending", nil)
+	r.ServeHTTP(w, req)
+
+	var b []IncomingRates
+	err := json.Unmarshal(w.Body.Bytes(), &b)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, m.pending, b)
+}
+
+func TestVersionHandlers(t *testing.T) {
+	versions := []Version{
+		{
+			ID:        1,
+			CreatedAt: time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC),
+			IncomingRates: IncomingRates{
+				Rates: []RateDetail{{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1500}},
+			},
+		},
+		{
+			ID:        2,
+			CreatedAt: time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC),
+			IncomingRates: IncomingRates{
+				Rates:   []RateDetail{{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 2000}},
+				Author:  "pricing",
+				Comment: "price increase",
+			},
+		},
+	}
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		outStatusCode int
+		outResponse   interface{}
+		response      interface{}
+	}{
+		{
+			name:          "list versions",
+			method:        "GET",
+			path:          "/rates/versions",
+			outStatusCode: 200,
+			outResponse: &VersionsResponse{
+				Status:   "success",
+				Message:  "success retrieving versions",
+				Versions: versions,
+			},
+			response: &VersionsResponse{},
+		},
+		{
+			name:          "get version",
+			method:        "GET",
+			path:          "/rates/versions/2",
+			outStatusCode: 200,
+			outResponse: &VersionResponse{
+				Status:  "success",
+				Message: "success retrieving version",
+				Version: &versions[1],
+			},
+			response: &VersionResponse{},
+		},
+		{
+			name:          "get unknown version",
+			method:        "GET",
+			path:          "/rates/versions/3",
+			outStatusCode: 404,
+			outResponse: &VersionResponse{
+				Status:  "error",
+				Message: "version not found",
+			},
+			response: &VersionResponse{},
+		},
+		{
+			name:          "get invalid version",
+			method:        "GET",
+			path:          "/rates/versions/latest",
+			outStatusCode: 400,
+			outResponse: &VersionResponse{
+				Status:  "error",
+				Message: "invalid version id: latest",
+			},
+			response: &VersionResponse{},
+		},
+		{
+			name:          "rollback",
+			method:        "POST",
+			path:          "/rates/versions/1/rollback?author=ops",
+			outStatusCode: 201,
+			outResponse: &VersionResponse{
+				Status:  "success",
+				Message: "Successfully rolled back to version 1",
+				Version: &Version{
+					ID:        3,
+					CreatedAt: versions[0].CreatedAt,
+					IncomingRates: IncomingRates{
+						Rates:   versions[0].Rates,
+						Author:  "ops",
+						Comment: "rollback to version 1",
+					},
+				},
+			},
+			response: &VersionResponse{},
+		},
+		{
+			name:          "rollback to unknown version",
+			method:        "POST",
+			path:          "/rates/versions/3/rollback",
+			outStatusCode: 404,
+			outResponse: &VersionResponse{
+				Status:  "error",
+				Message: "version not found",
+			},
+			response: &VersionResponse{},
+		},
+	}
+	for _, tt := range testCases {
+		m := &mockService{versions: versions}
+		r := NewRouter(m)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(tt.method, tt.path, nil)
+		r.ServeHTTP(w, req)
+
+		err := json.Unmarshal(w.Body.Bytes(), tt.response)
+		assert.Nil(t, err, tt.name)
+
+		assert.Equal(t, tt.outStatusCode, w.Code, tt.name)
+		assert.Equal(t, tt.outResponse, tt.response, tt.name)
+	}
+}
+
+func TestGetAuditHandler(t *testing.T) {
+	records := []AuditRecord{
+		{
+			Time:     time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC),
+			Caller:   "ops",
+			Role:     RoleAdmin,
+			Version:  2,
+			Previous: IncomingRates{Rates: []RateDetail{{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1500}}},
+			New:      IncomingRates{Rates: []RateDetail{{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 2000}}},
+			Diff: []DayDiff{{
+				Day: "Monday",
+				Changed: []Change{{
+					Before: RateDetail{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+					After:  RateDetail{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 2000},
+				}},
+			}},
+		},
+	}
+	testCases := []struct {
+		name          string
+		m             *mockService
+		path          string
+		since         time.Time
+		outStatusCode int
+		outResponse   AuditResponse
+	}{
+		{
+			name:          "get audit log",
+			m:             &mockService{records: records},
+			path:          "/rates/audit",
+			outStatusCode: 200,
+			outResponse: AuditResponse{
+				Status:  "success",
+				Message: "success retrieving audit log",
+				Records: records,
+			},
+		},
+		{
+			name:          "get audit log since a time",
+			m:             &mockService{records: records},
+			path:          "/rates/audit?since=2020-04-02T07:00:00-05:00",
+			since:         time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC),
+			outStatusCode: 200,
+			outResponse: AuditResponse{
+				Status:  "success",
+				Message: "success retrieving audit log",
+				Records: records,
+			},
+		},
+		{
+			name:          "invalid since",
+			m:             &mockService{},
+			path:          "/rates/audit?since=yesterday",
+			outStatusCode: 400,
+			outResponse: AuditResponse{
+				Status:  "error",
+				Message: "since must be an RFC3339 time: yesterday",
+			},
+		},
+		{
+			name:          "audit log disabled",
+			m:             &mockService{err: ErrNoAuditLog},
+			path:          "/rates/audit",
+			outStatusCode: 404,
+			outResponse: AuditResponse{
+				Status:  "error",
+				Message: "audit log is not enabled",
+			},
+		},
+		{
+			name:          "audit log fails",
+			m:             &mockService{err: errors.New("Simulating error reading the audit log")},
+			path:          "/rates/audit",
+			outStatusCode: 500,
+			outResponse: AuditResponse{
+				Status:  "error",
+				Message: "Simulating error reading the audit log",
+			},
+		},
+	}
+	for _, tt := range testCases {
+		r := NewRouter(tt.m)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		r.ServeHTTP(w, req)
+
+		var b AuditResponse
+		err := json.Unmarshal(w.Body.Bytes(), &b)
+		assert.Nil(t, err, tt.name)
+
+		assert.Equal(t, tt.outStatusCode, w.Code, tt.name)
+		assert.Equal(t, tt.outResponse, b, tt.name)
+		if tt.outStatusCode == 200 {
+			assert.True(t, tt.since.Equal(tt.m.lastDay), tt.name)
+		}
+	}
+}
+
+type mockService struct {
+	Service
+	putCallCount int
+	getCallCount int
+	rate         int
+	rates        IncomingRates
+	lastRates    IncomingRates
+	versions     []Version
+	pending      []IncomingRates
+	segments     []Segment
+	windows      []Window
+	lastRequest  ParkingTimesRequest
+	candidates   []Candidate
+	lastDay      time.Time
+	lastDuration time.Duration
+	calendar     []CalendarDay
+	records      []AuditRecord
+	diff         RateDiff
+	lastPatch    RatePatch
+	lastCaller   Caller
+	err          error
+}
+
+func (m *mockService) Put(ir IncomingRates) error {
+	m.lastRates = ir
+	if m.err != nil {
+		return m.err
+	}
+	m.putCallCount++
+	return nil
+}
+
+func (m *mockService) Patch(p RatePatch) (IncomingRates, error) {
+	m.lastPatch = p
+	if m.err != nil {
+		return IncomingRates{}, m.err
+	}
+	return m.rates, nil
+}
+
+func (m *mockService) Diff(ir IncomingRates) (RateDiff, error) {
+	m.lastRates = ir
+	if m.err != nil {
+		return RateDiff{}, m.err
+	}
+	return m.diff, nil
+}
+
+func (m *mockService) Quote(p ParkingTimesRequest) (Quote, error) {
+	if m.err != nil {
+		return Quote{}, m.err
+	}
+	m.getCallCount++
+	m.lastRequest = p
+	return Quote{Rate: m.rate, Segments: m.segments, Windows: m.windows}, nil
+}
+
+func (m *mockService) QuoteBatch(ps []ParkingTimesRequest) []QuoteResult {
+	results := make([]QuoteResult, len(ps))
+	for i, p := range ps {
+		q, err := m.Quote(p)
+		results[i] = QuoteResult{Quote: q, Err: err}
+	}
+	return results
+}
+
+func (m *mockService) Search(day time.Time, d time.Duration) ([]Candidate, error) {
+	m.lastDay = day
+	m.lastDuration = d
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.candidates, nil
+}
+
+func (m *mockService) Calendar(week time.Time) []CalendarDay {
+	m.lastDay = week
+	return m.calendar
+}
+
+func (m *mockService) Get(p ParkingTimesRequest) (rate int, err error) {
+	if m.err != nil {
 		return 0, m.err
 	}
 	m.getCallCount++
 	return m.rate, nil
 }
+
+func (m *mockService) List() IncomingRates {
+	return m.rates
+}
+
+func (m *mockService) Pending() []IncomingRates {
+	return m.pending
+}
+
+func (m *mockService) Versions() []Version {
+	return m.versions
+}
+
+func (m *mockService) Version(id int) (Version, error) {
+	if id < 1 || id > len(m.versions) {
+		return Version{}, ErrVersionNotFound
+	}
+	return m.versions[id-1], nil
+}
+
+func (m *mockService) Rollback(id int, author string, caller Caller) (Version, error) {
+	v, err := m.Version(id)
+	if err != nil {
+		return Version{}, err
+	}
+	v.ID = len(m.versions) + 1
+	v.Author = author
+	v.Comment = fmt.Sprintf("rollback to version %d", id)
+	m.lastCaller = caller
+	return v, nil
+}
+
+func (m *mockService) Audit(since time.Time) ([]AuditRecord, error) {
+	m.lastDay = since
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.records, nil
+}
+
+func TestFacilityHandlers(t *testing.T) {
+	fs := NewFacilities()
+	r := NewRouterWithOptions(&mockService{}, RouterOptions{Facilities: fs})
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		body          string
+		outStatusCode int
+		outResponse   interface{}
+		response      interface{}
+	}{
+		{
+			name:          "create facility",
+			method:        "POST",
+			path:          "/facilities",
+			body:          `{"id": "downtown", "name": "Downtown Garage", "tz": "America/Chicago"}`,
+			outStatusCode: 201,
+			outResponse: &FacilityResponse{
+				Status:   "success",
+				Message:  "Successfully created facility",
+				Facility: &Facility{ID: "downtown", Name: "Downtown Garage", TZ: "America/Chicago"},
+			},
+			response: &FacilityResponse{},
+		},
+		{
+			name:          "create existing facility",
+			method:        "POST",
+			path:          "/facilities",
+			body:          `{"id": "downtown"}`,
+			outStatusCode: 409,
+			outResponse: &FacilityResponse{
+				Status:  "error",
+				Message: "facility already exists",
+			},
+			response: &FacilityResponse{},
+		},
+		{
+			name:          "create facility without id",
+			method:        "POST",
+			path:          "/facilities",
+			body:          `{"name": "Uptown Garage"}`,
+			outStatusCode: 400,
+			outResponse: &FacilityResponse{
+				Status:  "error",
+				Message: "facility id is required",
+			},
+			response: &FacilityResponse{},
+		},
+		{
+			name:          "put facility rates",
+			method:        "PUT",
+			path:          "/facilities/downtown/rates",
+			body:          `{"rates": [{"days": "mon", "times": "0900-1700", "price": 1500}]}`,
+			outStatusCode: 200,
+			outResponse: &PutResponse{
+				Status:  "success",
+				Message: "Successfully updated rates",
+			},
+			response: &PutResponse{},
+		},
+		{
+			name:          "get facility rate",
+			method:        "GET",
+			path:          "/facilities/downtown/rate?start_time=2015-07-06T10:00:00-05:00&end_time=2015-07-06T11:00:00-05:00",
+			outStatusCode: 200,
+			outResponse: &RateResponse{
+				Status:  "success",
+				Message: "success retrieving rate",
+				Rate:    1500,
+			},
+			response: &RateResponse{},
+		},
+		{
+			name:          "get rate of unknown facility",
+			method:        "GET",
+			path:          "/facilities/uptown/rate?start_time=2015-07-06T10:00:00-05:00&end_time=2015-07-06T11:00:00-05:00",
+			outStatusCode: 404,
+			outResponse: &FacilityResponse{
+				Status:  "error",
+				Message: "facility not found",
+			},
+			response: &FacilityResponse{},
+		},
+		{
+			name:          "update facility",
+			method:        "PUT",
+			path:          "/facilities/downtown",
+			body:          `{"name": "Downtown Parking", "tz": "America/Chicago"}`,
+			outStatusCode: 200,
+			outResponse: &FacilityResponse{
+				Status:   "success",
+				Message:  "Successfully updated facility",
+				Facility: &Facility{ID: "downtown", Name: "Downtown Parking", TZ: "America/Chicago"},
+			},
+			response: &FacilityResponse{},
+		},
+		{
+			name:          "list facilities",
+			method:        "GET",
+			path:          "/facilities",
+			outStatusCode: 200,
+			outResponse: &FacilitiesResponse{
+				Status:     "success",
+				Message:    "success retrieving facilities",
+				Facilities: []Facility{{ID: "downtown", Name: "Downtown Parking", TZ: "America/Chicago"}},
+			},
+			response: &FacilitiesResponse{},
+		},
+		{
+			name:          "delete facility",
+			method:        "DELETE",
+			path:          "/facilities/downtown",
+			outStatusCode: 200,
+			outResponse: &FacilityResponse{
+				Status:  "success",
+				Message: "Successfully deleted facility",
+			},
+			response: &FacilityResponse{},
+		},
+		{
+			name:          "get deleted facility",
+			method:        "GET",
+			path:          "/facilities/downtown",
+			outStatusCode: 404,
+			outResponse: &FacilityResponse{
+				Status:  "error",
+				Message: "facility not found",
+			},
+			response: &FacilityResponse{},
+		},
+	}
+
+	for _, tt := range testCases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+		r.ServeHTTP(w, req)
+
+		err := json.Unmarshal(w.Body.Bytes(), tt.response)
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, tt.outStatusCode, w.Code, tt.name)
+		assert.Equal(t, tt.outResponse, tt.response, tt.name)
+	}
+}
+
+func TestFacilityAuditHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	l := NewFileAuditLog(filepath.Join(dir, "audit.jsonl"))
+	fs, err := NewFacilitiesWithOptions(FacilityOptions{
+		Rates: func(id string) Options {
+			return Options{Audit: l}
+		},
+	})
+	assert.Nil(t, err)
+	_, err = fs.CreateFacility(Facility{ID: "downtown", TZ: "America/Chicago"})
+	assert.Nil(t, err)
+	r := NewRouterWithOptions(&mockService{}, RouterOptions{Facilities: fs})
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{"PUT", "/facilities/downtown/rates", `{"rates": [{"days": "mon", "times": "0900-1700", "price": 1500}]}`},
+		{"PATCH", "/facilities/downtown/rates", `{"add": [{"days": "tues", "times": "0900-1700", "price": 1500}]}`},
+		{"POST", "/facilities/downtown/rates/holidays", "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;VALUE=DATE:20201225\nRRULE:FREQ=YEARLY\nSUMMARY:Christmas Day\nEND:VEVENT\nEND:VCALENDAR"},
+	}
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		httpReq, _ := http.NewRequest(req.method, req.path, strings.NewReader(req.body))
+		r.ServeHTTP(w, httpReq)
+		assert.Equal(t, 200, w.Code, req.path)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/facilities/downtown/rates/audit", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	var b AuditResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &b))
+	// assert that the put, the patch and the holidays of the facility are audited with its id
+	assert.Len(t, b.Records, 3)
+	for i, record := range b.Records {
+		assert.Equal(t, "downtown", record.FacilityID)
+		assert.Equal(t, i+2, record.Version)
+	}
+	assert.Equal(t, "Christmas Day", b.Records[2].Overrides[0].Name)
+
+	// assert that the records of a facility aren't returned with the rates at /rates that share the audit log
+	a, err := NewAPIWithOptions("seed_rates.json", Options{Audit: l})
+	assert.Nil(t, err)
+	records, err := a.Audit(time.Time{})
+	assert.Nil(t, err)
+	assert.Empty(t, records)
+}
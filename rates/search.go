@@ -0,0 +1,59 @@
+package rates
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Candidate is a time range of the requested duration and the rate for parking during it
+type Candidate struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Rate      int       `json:"rate"`
+}
+
+// Search returns the time ranges of duration d that start on the date of day, in the timezone of day,
+// and can be parked for, ordered by their rate and then by their start time.
+// A candidate starts at the start of each window that is in force on that date, or at midnight for a
+// window that started on the previous day, or when the rates of the window take effect.
+// It is priced the same way as Quote would price it, so it is left out unless a single window contains it.
+func (a *API) Search(day time.Time, d time.Duration) ([]Candidate, error) {
+	if d <= 0 {
+		return nil, errors.New("duration must be positive")
+	}
+	dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	dayEnd := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())
+
+	tables := a.snapshot()
+	seen := make(map[int64]bool)
+	candidates := []Candidate{}
+	for _, s := range spansIn(tables, dayStart, dayEnd) {
+		if seen[s.start.UnixNano()] {
+			continue
+		}
+		p := ParkingTimesRequest{
+			StartTime: s.start.In(day.Location()),
+			EndTime:   s.start.Add(d).In(day.Location()),
+		}
+		// The candidate is priced by the table that is in force at its start,
+		// which resolves overlapping rates and expired rates like any other quote
+		q, err := a.quote(tables, p)
+		if err != nil {
+			continue
+		}
+		seen[s.start.UnixNano()] = true
+		candidates = append(candidates, Candidate{
+			StartTime: p.StartTime,
+			EndTime:   p.EndTime,
+			Rate:      q.Rate,
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Rate != candidates[j].Rate {
+			return candidates[i].Rate < candidates[j].Rate
+		}
+		return candidates[i].StartTime.Before(candidates[j].StartTime)
+	})
+	return candidates, nil
+}
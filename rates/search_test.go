@@ -0,0 +1,95 @@
+package rates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	chicago, err := time.LoadLocation("America/Chicago")
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		name       string
+		day        time.Time
+		d          time.Duration
+		candidates []Candidate
+	}{
+		{
+			name: "Candidates are ordered by rate",
+			day:  time.Date(2020, 4, 1, 0, 0, 0, 0, chicago),
+			d:    2 * time.Hour,
+			candidates: []Candidate{
+				{StartTime: time.Date(2020, 4, 1, 1, 0, 0, 0, chicago), EndTime: time.Date(2020, 4, 1, 3, 0, 0, 0, chicago), Rate: 1000},
+				{StartTime: time.Date(2020, 4, 1, 6, 0, 0, 0, chicago), EndTime: time.Date(2020, 4, 1, 8, 0, 0, 0, chicago), Rate: 1750},
+			},
+		},
+		{
+			name: "Windows that are shorter than the duration are left out",
+			day:  time.Date(2020, 4, 1, 0, 0, 0, 0, chicago),
+			d:    5 * time.Hour,
+			candidates: []Candidate{
+				{StartTime: time.Date(2020, 4, 1, 6, 0, 0, 0, chicago), EndTime: time.Date(2020, 4, 1, 11, 0, 0, 0, chicago), Rate: 1750},
+			},
+		},
+		{
+			name: "The date is in the timezone of the search, a window that started the day before starts at midnight",
+			day:  time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC),
+			d:    2 * time.Hour,
+			candidates: []Candidate{
+				{StartTime: time.Date(2020, 4, 1, 6, 0, 0, 0, time.UTC), EndTime: time.Date(2020, 4, 1, 8, 0, 0, 0, time.UTC), Rate: 1000},
+				{StartTime: time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC), EndTime: time.Date(2020, 4, 1, 2, 0, 0, 0, time.UTC), Rate: 1500},
+				{StartTime: time.Date(2020, 4, 1, 11, 0, 0, 0, time.UTC), EndTime: time.Date(2020, 4, 1, 13, 0, 0, 0, time.UTC), Rate: 1750},
+			},
+		},
+		{
+			name:       "No window is long enough",
+			day:        time.Date(2020, 4, 1, 0, 0, 0, 0, chicago),
+			d:          13 * time.Hour,
+			candidates: []Candidate{},
+		},
+	}
+	for _, tt := range testCases {
+		candidates, err := a.Search(tt.day, tt.d)
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, len(tt.candidates), len(candidates), tt.name)
+		for i := range candidates {
+			if i >= len(tt.candidates) {
+				break
+			}
+			assert.True(t, tt.candidates[i].StartTime.Equal(candidates[i].StartTime), tt.name)
+			assert.True(t, tt.candidates[i].EndTime.Equal(candidates[i].EndTime), tt.name)
+			assert.Equal(t, tt.candidates[i].Rate, candidates[i].Rate, tt.name)
+		}
+	}
+
+	_, err = a.Search(time.Date(2020, 4, 1, 0, 0, 0, 0, chicago), 0)
+	assert.EqualError(t, err, "duration must be positive")
+}
+
+func TestSearchAcrossScheduledRates(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	// Rates that take effect at noon replace the seed rates for the rest of the day
+	effectiveFrom := nextWeekday(time.Now(), time.Wednesday, 12, time.UTC)
+	err = a.Put(IncomingRates{
+		Rates:         []RateDetail{{Days: "wed", Times: "0000-2400", TZ: "UTC", Price: 500}},
+		EffectiveFrom: &effectiveFrom,
+	})
+	assert.Nil(t, err)
+
+	day := effectiveFrom.Add(-12 * time.Hour)
+	candidates, err := a.Search(day, time.Hour)
+	assert.Nil(t, err)
+	// The seed rates before noon are priced by the seed rates, and the window of
+	// the new rates starts when they take effect
+	assert.Equal(t, 500, candidates[0].Rate)
+	assert.True(t, effectiveFrom.Equal(candidates[0].StartTime))
+	for _, c := range candidates[1:] {
+		assert.True(t, c.StartTime.Before(effectiveFrom))
+	}
+}
@@ -20,6 +20,10 @@ var (
 		Name: "put_rate_bad_request_count",
 		Help: "The total number of PUT requests that were a bad request",
 	})
+	putConflict = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "put_rate_conflict_count",
+		Help: "The total number of PUT requests that were rejected for overlapping rates",
+	})
 	get200Ok = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "get_rate_success_count",
 		Help: "The total number of succesfully processed GET requests",
@@ -32,6 +36,10 @@ var (
 		Name: "get_rate_400_count",
 		Help: "The total number of GET requests that resulted in a 400",
 	})
+	list200Ok = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "list_rates_success_count",
+		Help: "The total number of succesfully processed GET requests for the active rates",
+	})
 
 	requestDurationGet = prometheus.NewHistogram(prometheus.HistogramOpts{
 		Name:    "request_duration_seconds_get",
@@ -60,6 +68,11 @@ func recordPutBadRequest() {
 	putBadRequest.Inc()
 }
 
+// record a put request with overlapping rates (422 error)
+func recordPutConflict() {
+	putConflict.Inc()
+}
+
 // record a 404 on a get request
 func recordGetRatetNotFound() {
 	get404NotFound.Inc()
@@ -75,6 +88,11 @@ func recordGetRateBadRequest() {
 	get400BadRequest.Inc()
 }
 
+// record a successful 200 OK on a list rates request
+func recordListRatesSuccess() {
+	list200Ok.Inc()
+}
+
 func init() {
 	// These metrics have to be registered to be exposed:
 	prometheus.MustRegister(requestDurationGet)
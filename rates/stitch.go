@@ -0,0 +1,288 @@
+package rates
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+const (
+	// StitchSum prices a time range that is stitched from several windows as the sum of their prices
+	StitchSum = "sum"
+	// StitchMax prices a time range that is stitched from several windows as the highest of their prices
+	StitchMax = "max"
+)
+
+// Window is a rate window that was used to price part of a time range when windows are stitched together
+// Index is the index of the rate in IncomingRates and Rate is the price charged for the part.
+type Window struct {
+	Index     int       `json:"index" xml:"index"`
+	Override  string    `json:"override,omitempty" xml:"override,omitempty"`
+	Day       string    `json:"day" xml:"day"`
+	Times     string    `json:"times" xml:"times"`
+	TZ        string    `json:"tz" xml:"tz"`
+	StartTime time.Time `json:"start_time" xml:"start_time"`
+	EndTime   time.Time `json:"end_time" xml:"end_time"`
+	Rate      int       `json:"rate" xml:"rate"`
+}
+
+// validStitch checks if mode is one of the supported ways of stitching windows together
+func validStitch(mode string) bool {
+	return mode == "" || mode == StitchSum || mode == StitchMax
+}
+
+// spansBetween returns the rates of the table resolved to absolute time ranges on the dates from start
+// to end, clipped to start and end. Rates that don't intersect the time range are left out, and so are
+// the weekday rates of every timezone on the dates that an override is in force on.
+func (t *rateTable) spansBetween(start, end time.Time) []span {
+	var spans []span
+	// overridden holds the dates that an override is in force on, from midnight to midnight in its timezone
+	var overridden []span
+	for _, loc := range t.locations {
+		localStart := start.In(loc)
+		localEnd := end.In(loc)
+		// Walk every local date that the time range touches
+		for d := 0; ; d++ {
+			y, m, day := localStart.Date()
+			date := time.Date(y, m, day+d, 0, 0, 0, 0, loc)
+			if !date.Before(localEnd) {
+				break
+			}
+			rates := t.rateMap[date.Weekday().String()]
+			// An override of the date takes precedence over the rates of the weekday
+			if o := t.overrideOn(date, loc); o != nil {
+				rates = o.rates
+				overridden = append(overridden, span{start: date, end: time.Date(y, m, day+d+1, 0, 0, 0, 0, loc)})
+			}
+			for _, r := range rates {
+				if r.loc.String() != loc.String() {
+					continue
+				}
+				r.day = date.Weekday().String()
+				// The seconds since midnight are normalized by time.Date into the wall clock time on the date
+				s := span{
+					start: time.Date(date.Year(), date.Month(), date.Day(), 0, 0, r.startTime, 0, loc),
+					end:   time.Date(date.Year(), date.Month(), date.Day(), 0, 0, r.endTime, 0, loc),
+					rate:  r,
+				}
+				// The rate stops being in force at its effective_until time
+				if !r.effectiveUntil.IsZero() && s.end.After(r.effectiveUntil) {
+					s.end = r.effectiveUntil
+				}
+				if s.start.Before(start) {
+					s.start = start
+				}
+				if s.end.After(end) {
+					s.end = end
+				}
+				if s.start.Before(s.end) {
+					spans = append(spans, s)
+				}
+			}
+		}
+	}
+	if len(overridden) == 0 {
+		return spans
+	}
+	var kept []span
+	for _, s := range spans {
+		if s.rate.override != "" {
+			kept = append(kept, s)
+			continue
+		}
+		kept = append(kept, withoutSpans(s, overridden)...)
+	}
+	return kept
+}
+
+// withoutSpans returns the parts of s that none of the spans of others intersect
+func withoutSpans(s span, others []span) []span {
+	parts := []span{s}
+	for _, o := range others {
+		var next []span
+		for _, p := range parts {
+			if !o.start.Before(p.end) || !p.start.Before(o.end) {
+				next = append(next, p)
+				continue
+			}
+			if p.start.Before(o.start) {
+				before := p
+				before.end = o.start
+				next = append(next, before)
+			}
+			if o.end.Before(p.end) {
+				after := p
+				after.start = o.end
+				next = append(next, after)
+			}
+		}
+		parts = next
+	}
+	return parts
+}
+
+// spansIn returns the rates of every table that is in force at some point from start to end, resolved
+// to absolute time ranges. The time ranges of each table are clipped to the part that it is in force for.
+func spansIn(tables []*rateTable, start, end time.Time) []span {
+	var spans []span
+	for i, t := range tables {
+		if !t.effectiveFrom.Before(end) {
+			break
+		}
+		from, until := start, end
+		if t.effectiveFrom.After(from) {
+			from = t.effectiveFrom
+		}
+		if i+1 < len(tables) && tables[i+1].effectiveFrom.Before(until) {
+			until = tables[i+1].effectiveFrom
+		}
+		if from.Before(until) {
+			spans = append(spans, t.spansBetween(from, until)...)
+		}
+	}
+	return spans
+}
+
+// stitchRate prices a time range that lies within a single day by stitching together the windows that
+// cover it. Of all the ways that contiguous windows can cover the time range, the one with the lowest
+// price is used, which is the sum or the highest of the prices of its windows depending on mode.
+// Overlapping windows are first resolved by the overlap policy of the table, like they are for a single window.
+// offset is how long the car has already been parked for before start, and daily holds what the rates have
+// already charged on the days of the earlier segments. The charges of the windows are added to it.
+func (a *API) stitchRate(t *rateTable, mode string, start, end time.Time, offset time.Duration, daily dailyCharges) (int, []Window, error) {
+	spans := t.spansBetween(start, end)
+
+	// The time range can only be split where a window starts or ends
+	points := []time.Time{start, end}
+	for _, s := range spans {
+		points = append(points, s.start, s.end)
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Before(points[j])
+	})
+	unique := points[:1]
+	for _, p := range points[1:] {
+		if !p.Equal(unique[len(unique)-1]) {
+			unique = append(unique, p)
+		}
+	}
+	points = unique
+	if t.allowOverlap != "" {
+		spans = resolveOverlaps(spans, points, t.allowOverlap, start, offset, daily)
+	}
+
+	// best holds the cheapest way found so far to cover the time range from start up to each point
+	type step struct {
+		reached bool
+		price   int
+		windows int
+		prev    int
+		rate    DayRate
+		window  Window
+	}
+	best := make([]step, len(points))
+	best[0].reached = true
+	for i := range points {
+		if !best[i].reached {
+			continue
+		}
+		for _, s := range spans {
+			// The window has to cover the point to continue from it
+			if s.start.After(points[i]) || !s.end.After(points[i]) {
+				continue
+			}
+			for j := i + 1; j < len(points) && !points[j].After(s.end); j++ {
+				charge := s.rate.charge(points[i], points[j], offset+points[i].Sub(start), daily)
+				price := best[i].price + charge
+				if mode == StitchMax {
+					price = best[i].price
+					if charge > price {
+						price = charge
+					}
+				}
+				windows := best[i].windows + 1
+				// Prefer the lowest price and then the fewest windows
+				if best[j].reached && (price > best[j].price || (price == best[j].price && windows >= best[j].windows)) {
+					continue
+				}
+				best[j] = step{
+					reached: true,
+					price:   price,
+					windows: windows,
+					prev:    i,
+					rate:    s.rate,
+					window: Window{
+						Index:     s.rate.index,
+						Override:  s.rate.override,
+						Day:       s.rate.day,
+						Times:     formatTimes(s.rate.startTime, s.rate.endTime),
+						TZ:        s.rate.tz,
+						StartTime: points[i].In(start.Location()),
+						EndTime:   points[j].In(start.Location()),
+						Rate:      charge,
+					},
+				}
+			}
+		}
+	}
+
+	last := len(points) - 1
+	if !best[last].reached {
+		return 0, nil, errors.New("unavailable")
+	}
+	// Walk back from the end of the time range to collect the windows in order
+	windows := make([]Window, best[last].windows)
+	// highest is the step of the window with the highest charge, which is the only one charged by max
+	highest := last
+	for i, w := last, len(windows)-1; i > 0; i, w = best[i].prev, w-1 {
+		windows[w] = best[i].window
+		if mode != StitchMax {
+			daily.add(best[i].rate, best[i].window.StartTime, best[i].window.Rate)
+		} else if best[i].window.Rate > best[highest].window.Rate {
+			highest = i
+		}
+	}
+	if mode == StitchMax {
+		daily.add(best[highest].rate, best[highest].window.StartTime, best[highest].window.Rate)
+	}
+	return best[last].price, windows, nil
+}
+
+// resolveOverlaps returns spans with every time range between consecutive points that several spans cover
+// left to only one of them, the span whose charge for that time range policy prefers. points are the sorted
+// instants that the spans start and end at. Consecutive parts of the same span are joined back together,
+// so a span that wins all of its time ranges is charged once for the whole of it.
+func resolveOverlaps(spans []span, points []time.Time, policy string, start time.Time, offset time.Duration, daily dailyCharges) []span {
+	var resolved []span
+	// last is the index among spans of the span that the previous time range was left to
+	last := -1
+	for i := 0; i+1 < len(points); i++ {
+		from, to := points[i], points[i+1]
+		chosen := -1
+		var price int
+		for k, s := range spans {
+			if s.start.After(from) || s.end.Before(to) {
+				continue
+			}
+			charge := s.rate.charge(from, to, offset+from.Sub(start), daily)
+			if chosen < 0 || preferredPrice(policy, charge, price) {
+				chosen = k
+				price = charge
+			}
+		}
+		if chosen < 0 {
+			last = -1
+			continue
+		}
+		if chosen == last {
+			resolved[len(resolved)-1].end = to
+			continue
+		}
+		part := spans[chosen]
+		part.start = from
+		part.end = to
+		resolved = append(resolved, part)
+		last = chosen
+	}
+	return resolved
+}
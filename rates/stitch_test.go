@@ -0,0 +1,286 @@
+package rates
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteStitch(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon", Times: "0600-0900", TZ: "UTC", Price: 1000},
+			{Days: "mon", Times: "0900-2100", TZ: "UTC", Price: 1500},
+			{Days: "mon", Times: "2100-2400", TZ: "UTC", Price: 500},
+			{Days: "tues", Times: "0000-0600", TZ: "UTC", Price: 700},
+			{Days: "wed", Times: "0600-0900", TZ: "UTC", Price: 1000},
+			{Days: "wed", Times: "1000-2100", TZ: "UTC", Price: 1500},
+		},
+	})
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		name    string
+		p       ParkingTimesRequest
+		rate    int
+		windows []Window
+		err     error
+	}{
+		{
+			name: "Unavailable without stitching",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 6, 8, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 6, 10, 0, 0, 0, time.UTC),
+			},
+			err: errors.New("unavailable"),
+		},
+		{
+			name: "Sum of two windows",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 6, 8, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 6, 10, 0, 0, 0, time.UTC),
+				Stitch:    StitchSum,
+			},
+			rate: 2500,
+			windows: []Window{
+				{
+					Index:     0,
+					Day:       "Monday",
+					Times:     "0600-0900",
+					TZ:        "UTC",
+					StartTime: time.Date(2020, 4, 6, 8, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 6, 9, 0, 0, 0, time.UTC),
+					Rate:      1000,
+				},
+				{
+					Index:     1,
+					Day:       "Monday",
+					Times:     "0900-2100",
+					TZ:        "UTC",
+					StartTime: time.Date(2020, 4, 6, 9, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 6, 10, 0, 0, 0, time.UTC),
+					Rate:      1500,
+				},
+			},
+		},
+		{
+			name: "Max of three windows",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 6, 8, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 6, 22, 0, 0, 0, time.UTC),
+				Stitch:    StitchMax,
+			},
+			rate: 1500,
+			windows: []Window{
+				{
+					Index:     0,
+					Day:       "Monday",
+					Times:     "0600-0900",
+					TZ:        "UTC",
+					StartTime: time.Date(2020, 4, 6, 8, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 6, 9, 0, 0, 0, time.UTC),
+					Rate:      1000,
+				},
+				{
+					Index:     1,
+					Day:       "Monday",
+					Times:     "0900-2100",
+					TZ:        "UTC",
+					StartTime: time.Date(2020, 4, 6, 9, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 6, 21, 0, 0, 0, time.UTC),
+					Rate:      1500,
+				},
+				{
+					Index:     2,
+					Day:       "Monday",
+					Times:     "2100-2400",
+					TZ:        "UTC",
+					StartTime: time.Date(2020, 4, 6, 21, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 6, 22, 0, 0, 0, time.UTC),
+					Rate:      500,
+				},
+			},
+		},
+		{
+			name: "A single window that contains the time range",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 6, 10, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 6, 12, 0, 0, 0, time.UTC),
+				Stitch:    StitchSum,
+			},
+			rate: 1500,
+			windows: []Window{
+				{
+					Index:     1,
+					Day:       "Monday",
+					Times:     "0900-2100",
+					TZ:        "UTC",
+					StartTime: time.Date(2020, 4, 6, 10, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 6, 12, 0, 0, 0, time.UTC),
+					Rate:      1500,
+				},
+			},
+		},
+		{
+			name: "Unavailable when there is a gap between the windows",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 8, 8, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 8, 11, 0, 0, 0, time.UTC),
+				Stitch:    StitchSum,
+			},
+			err: errors.New("unavailable"),
+		},
+		{
+			name: "Multi day, across midnight",
+			p: ParkingTimesRequest{
+				StartTime: time.Date(2020, 4, 6, 20, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2020, 4, 7, 2, 0, 0, 0, time.UTC),
+				MultiDay:  true,
+				Stitch:    StitchSum,
+			},
+			rate: 2700,
+			windows: []Window{
+				{
+					Index:     1,
+					Day:       "Monday",
+					Times:     "0900-2100",
+					TZ:        "UTC",
+					StartTime: time.Date(2020, 4, 6, 20, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 6, 21, 0, 0, 0, time.UTC),
+					Rate:      1500,
+				},
+				{
+					Index:     2,
+					Day:       "Monday",
+					Times:     "2100-2400",
+					TZ:        "UTC",
+					StartTime: time.Date(2020, 4, 6, 21, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 7, 0, 0, 0, 0, time.UTC),
+					Rate:      500,
+				},
+				{
+					Index:     3,
+					Day:       "Tuesday",
+					Times:     "0000-0600",
+					TZ:        "UTC",
+					StartTime: time.Date(2020, 4, 7, 0, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(2020, 4, 7, 2, 0, 0, 0, time.UTC),
+					Rate:      700,
+				},
+			},
+		},
+	}
+	for _, tt := range testCases {
+		q, err := a.Quote(tt.p)
+		assert.Equal(t, tt.err, err, tt.name)
+		assert.Equal(t, tt.rate, q.Rate, tt.name)
+		assert.Equal(t, tt.windows, q.Windows, tt.name)
+	}
+
+	_, err = a.Quote(ParkingTimesRequest{
+		StartTime: time.Date(2020, 4, 6, 8, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2020, 4, 6, 10, 0, 0, 0, time.UTC),
+		Stitch:    "min",
+	})
+	assert.Equal(t, errors.New("unknown stitch mode: min"), err)
+}
+
+func TestQuoteStitchPicksTheLowestPrice(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	// The whole day window is more expensive than the two windows that cover the same time
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon", Times: "0000-2400", TZ: "UTC", Price: 5000},
+			{Days: "mon", Times: "0800-1200", TZ: "UTC", Price: 1000},
+			{Days: "mon", Times: "1200-1800", TZ: "UTC", Price: 1500},
+		},
+		AllowOverlap: OverlapLowest,
+	})
+	assert.Nil(t, err)
+
+	p := ParkingTimesRequest{
+		StartTime: time.Date(2020, 4, 6, 10, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2020, 4, 6, 14, 0, 0, 0, time.UTC),
+		Stitch:    StitchSum,
+	}
+	q, err := a.Quote(p)
+	assert.Nil(t, err)
+	assert.Equal(t, 2500, q.Rate)
+	assert.Len(t, q.Windows, 2)
+
+	// The two windows are still the lowest price when only the highest of their prices is charged
+	p.Stitch = StitchMax
+	q, err = a.Quote(p)
+	assert.Nil(t, err)
+	assert.Equal(t, 1500, q.Rate)
+	assert.Len(t, q.Windows, 2)
+}
+
+func TestQuoteStitchOverlapPolicy(t *testing.T) {
+	rates := []RateDetail{
+		{Days: "mon", Times: "0000-2400", TZ: "UTC", Price: 5000},
+		{Days: "mon", Times: "0800-1200", TZ: "UTC", Price: 1000},
+		{Days: "mon", Times: "1200-1800", TZ: "UTC", Price: 1500},
+	}
+	testCases := []struct {
+		name    string
+		policy  string
+		stitch  string
+		rate    int
+		windows []string
+	}{
+		{
+			name:    "Lowest policy summed",
+			policy:  OverlapLowest,
+			stitch:  StitchSum,
+			rate:    2500,
+			windows: []string{"0800-1200", "1200-1800"},
+		},
+		{
+			name:    "Lowest policy at the highest window",
+			policy:  OverlapLowest,
+			stitch:  StitchMax,
+			rate:    1500,
+			windows: []string{"0800-1200", "1200-1800"},
+		},
+		{
+			// The whole day window is the highest price over the whole time range, so it is the only window
+			name:    "Highest policy summed",
+			policy:  OverlapHighest,
+			stitch:  StitchSum,
+			rate:    5000,
+			windows: []string{"0000-2400"},
+		},
+		{
+			name:    "Highest policy at the highest window",
+			policy:  OverlapHighest,
+			stitch:  StitchMax,
+			rate:    5000,
+			windows: []string{"0000-2400"},
+		},
+	}
+	for _, tt := range testCases {
+		a, err := NewAPI("seed_rates.json")
+		assert.Nil(t, err)
+		err = a.Put(IncomingRates{Rates: rates, AllowOverlap: tt.policy})
+		assert.Nil(t, err, tt.name)
+
+		q, err := a.Quote(ParkingTimesRequest{
+			StartTime: time.Date(2020, 4, 6, 10, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2020, 4, 6, 14, 0, 0, 0, time.UTC),
+			Stitch:    tt.stitch,
+		})
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, tt.rate, q.Rate, tt.name)
+		var windows []string
+		for _, w := range q.Windows {
+			windows = append(windows, w.Times)
+		}
+		assert.Equal(t, tt.windows, windows, tt.name)
+	}
+}
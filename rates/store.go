@@ -0,0 +1,197 @@
+package rates
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNoRates is returned by a RateStore when no rates have been saved to it yet
+var ErrNoRates = errors.New("no rates stored")
+
+// FileStore is a RateStore that keeps the schedule of accepted rates in a JSON file
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a FileStore that saves rates to the file at path
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load reads the schedule from the file. It returns ErrNoRates if the file doesn't exist.
+func (f *FileStore) Load() ([]IncomingRates, error) {
+	bytes, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoRates
+	}
+	if err != nil {
+		return nil, err
+	}
+	var schedule []IncomingRates
+	err = json.Unmarshal(bytes, &schedule)
+	return schedule, err
+}
+
+// Save writes the schedule to a temporary file next to the file and then renames it over the file.
+// The rename is atomic, so the file always holds either the previous or the new schedule in full.
+func (f *FileStore) Save(schedule []IncomingRates) error {
+	return writeJSON(f.path, schedule)
+}
+
+// writeJSON writes v as JSON to a temporary file next to the file at path and then renames it over the file
+func writeJSON(path string, v interface{}) error {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	// Clean up the temporary file if anything fails before it is renamed
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bytes); err != nil {
+		tmp.Close()
+		return err
+	}
+	// Flush the new rates to disk before they replace the previous rates
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// FileFacilityStore is a FacilityStore that keeps the facilities in a JSON file
+type FileFacilityStore struct {
+	path string
+}
+
+// NewFileFacilityStore returns a FileFacilityStore that saves facilities to the file at path
+func NewFileFacilityStore(path string) *FileFacilityStore {
+	return &FileFacilityStore{path: path}
+}
+
+// LoadFacilities reads the facilities from the file. It returns no facilities if the file doesn't exist.
+func (f *FileFacilityStore) LoadFacilities() ([]Facility, error) {
+	bytes, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return []Facility{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var facilities []Facility
+	err = json.Unmarshal(bytes, &facilities)
+	return facilities, err
+}
+
+// SaveFacilities atomically replaces the facilities in the file, like FileStore.Save does for rates
+func (f *FileFacilityStore) SaveFacilities(facilities []Facility) error {
+	return writeJSON(f.path, facilities)
+}
+
+var (
+	// boltBucket is the bucket that the rates are stored in
+	boltBucket = []byte("rates")
+	// boltScheduleKey is the key of the schedule of accepted rates in the bucket
+	boltScheduleKey = []byte("schedule")
+	// boltFacilitiesKey is the key of the facilities in the bucket
+	boltFacilitiesKey = []byte("facilities")
+)
+
+// BoltStore is a RateStore that keeps the schedule of accepted rates in an embedded BoltDB database
+// It is also a FacilityStore that keeps the facilities in the same database.
+type BoltStore struct {
+	db *bolt.DB
+	// key is the key of the schedule in the bucket
+	key []byte
+}
+
+// NewBoltStore opens or creates the BoltDB database at path
+// It has to be closed with Close once it is no longer used
+func NewBoltStore(path string) (*BoltStore, error) {
+	// The timeout keeps the service from hanging if another process holds the lock on the database
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db, key: boltScheduleKey}, nil
+}
+
+// Facility returns a BoltStore that keeps the schedule of the facility with the given id in the same database
+// It shares the database with b, so only b has to be closed.
+func (b *BoltStore) Facility(id string) *BoltStore {
+	return &BoltStore{db: b.db, key: []byte("schedule/" + id)}
+}
+
+// Load reads the schedule from the database. It returns ErrNoRates if no rates have been saved yet.
+func (b *BoltStore) Load() ([]IncomingRates, error) {
+	var schedule []IncomingRates
+	err := b.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(boltBucket).Get(b.key)
+		if v == nil {
+			return ErrNoRates
+		}
+		return json.Unmarshal(v, &schedule)
+	})
+	return schedule, err
+}
+
+// Save writes the schedule to the database in a single transaction
+func (b *BoltStore) Save(schedule []IncomingRates) error {
+	bytes, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put(b.key, bytes)
+	})
+}
+
+// LoadFacilities reads the facilities from the database. It returns no facilities if none have been saved yet.
+func (b *BoltStore) LoadFacilities() ([]Facility, error) {
+	facilities := []Facility{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(boltBucket).Get(boltFacilitiesKey)
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &facilities)
+	})
+	return facilities, err
+}
+
+// SaveFacilities writes the facilities to the database in a single transaction
+func (b *BoltStore) SaveFacilities(facilities []Facility) error {
+	bytes, err := json.Marshal(facilities)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put(boltFacilitiesKey, bytes)
+	})
+}
+
+// Close closes the database
+func (b *BoltStore) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,159 @@
+package rates
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var storedRates = IncomingRates{
+	Rates: []RateDetail{
+		{ID: "weekdays", Days: "mon,tues,thurs", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+	},
+}
+
+var storedSchedule = []IncomingRates{storedRates}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rates.json")
+	s := NewFileStore(path)
+	_, err = s.Load()
+	assert.Equal(t, ErrNoRates, err)
+
+	err = s.Save(storedSchedule)
+	assert.Nil(t, err)
+	schedule, err := s.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, storedSchedule, schedule)
+
+	// assert that no temporary files are left behind next to the file
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestBoltStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rates.db")
+	s, err := NewBoltStore(path)
+	assert.Nil(t, err)
+	_, err = s.Load()
+	assert.Equal(t, ErrNoRates, err)
+
+	err = s.Save(storedSchedule)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Close())
+
+	// assert that the rates survive reopening the database
+	s, err = NewBoltStore(path)
+	assert.Nil(t, err)
+	defer s.Close()
+	schedule, err := s.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, storedSchedule, schedule)
+
+	// assert that the schedule of a facility is kept apart from the schedule of the rates
+	downtown := s.Facility("downtown")
+	_, err = downtown.Load()
+	assert.Equal(t, ErrNoRates, err)
+	assert.Nil(t, downtown.Save([]IncomingRates{{Rates: []RateDetail{}}}))
+	schedule, err = s.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, storedSchedule, schedule)
+
+	facilities, err := s.LoadFacilities()
+	assert.Nil(t, err)
+	assert.Empty(t, facilities)
+	assert.Nil(t, s.SaveFacilities([]Facility{{ID: "downtown", TZ: "America/Chicago"}}))
+	facilities, err = s.LoadFacilities()
+	assert.Nil(t, err)
+	assert.Equal(t, []Facility{{ID: "downtown", TZ: "America/Chicago"}}, facilities)
+}
+
+func TestNewAPIWithStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	s := NewFileStore(filepath.Join(dir, "rates.json"))
+
+	// assert that the API is seeded when the store is empty, without saving the seed rates
+	a, err := NewAPIWithOptions("seed_rates.json", Options{Store: s})
+	assert.Nil(t, err)
+	assert.Len(t, a.List().Rates, 5)
+	_, err = s.Load()
+	assert.Equal(t, ErrNoRates, err)
+
+	// assert that accepted rates are saved
+	err = a.Put(storedRates)
+	assert.Nil(t, err)
+	schedule, err := s.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, storedSchedule, schedule)
+
+	// assert that the stored rates are preferred over the seed rates on startup
+	a, err = NewAPIWithOptions("seed_rates.json", Options{Store: s})
+	assert.Nil(t, err)
+	assert.Equal(t, storedRates, a.List())
+}
+
+func TestNewAPIWithStoreKeepsPendingRates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	s := NewFileStore(filepath.Join(dir, "rates.json"))
+	a, err := NewAPIWithOptions("seed_rates.json", Options{Store: s})
+	assert.Nil(t, err)
+
+	effectiveFrom := time.Now().AddDate(0, 1, 0).UTC()
+	pending := IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 3000},
+		},
+		EffectiveFrom: &effectiveFrom,
+	}
+	err = a.Put(storedRates)
+	assert.Nil(t, err)
+	err = a.Put(pending)
+	assert.Nil(t, err)
+
+	// assert that both the active and the pending rates are restored on startup
+	a, err = NewAPIWithOptions("seed_rates.json", Options{Store: s})
+	assert.Nil(t, err)
+	assert.Equal(t, storedRates, a.List())
+	assert.Len(t, a.Pending(), 1)
+	assert.True(t, effectiveFrom.Equal(*a.Pending()[0].EffectiveFrom))
+}
+
+func TestPutWhenStoreFails(t *testing.T) {
+	a, err := NewAPIWithOptions("seed_rates.json", Options{Store: &failingStore{}})
+	assert.Nil(t, err)
+
+	err = a.Put(storedRates)
+	assert.Equal(t, errors.New("disk full"), err)
+	// assert that the rates that couldn't be saved were not put in place
+	assert.Len(t, a.List().Rates, 5)
+}
+
+type failingStore struct{}
+
+func (f *failingStore) Load() ([]IncomingRates, error) {
+	return nil, ErrNoRates
+}
+
+func (f *failingStore) Save(schedule []IncomingRates) error {
+	return errors.New("disk full")
+}
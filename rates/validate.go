@@ -0,0 +1,169 @@
+package rates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError describes a single invalid field of the rate at Index in IncomingRates.
+// The fields of an override start with overrides., in which case Index is the index of the override.
+type FieldError struct {
+	Index   int    `json:"index" xml:"index"`
+	Field   string `json:"field" xml:"field"`
+	Message string `json:"message" xml:"message"`
+}
+
+// ValidationError is returned when one or more rates are malformed
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid rates: %d field errors", len(e.Errors))
+}
+
+// Validate checks every rate for malformed fields and returns a ValidationError listing all of them
+func Validate(ir IncomingRates) error {
+	var errs []FieldError
+	// ids holds the ids of the rates that were checked so far
+	ids := make(map[string]bool)
+	for i, r := range ir.Rates {
+		add := func(field, format string, args ...interface{}) {
+			errs = append(errs, FieldError{
+				Index:   i,
+				Field:   field,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		if r.ID != "" {
+			if ids[r.ID] {
+				add("id", "duplicate id: %q", r.ID)
+			}
+			ids[r.ID] = true
+		}
+		for _, day := range strings.Split(r.Days, ",") {
+			if _, ok := dayMap[day]; !ok {
+				add("days", "unknown day: %q", day)
+			}
+		}
+		if _, _, err := parseTimes(r.Times); err != nil {
+			add("times", "%s", err)
+		}
+		if !validBoundary(r.Boundary) {
+			add("boundary", "unknown boundary: %q", r.Boundary)
+		}
+		if r.TZ == "" {
+			add("tz", "tz is required")
+		} else if _, err := time.LoadLocation(r.TZ); err != nil {
+			add("tz", "unknown timezone: %q", r.TZ)
+		}
+		if r.Price < 0 {
+			add("price", "price must not be negative")
+		}
+		validatePricing(r.Pricing, add)
+		if r.EffectiveUntil != nil && ir.EffectiveFrom != nil && !r.EffectiveUntil.After(*ir.EffectiveFrom) {
+			add("effective_until", "effective_until must be after effective_from")
+		}
+	}
+	errs = append(errs, validateOverrides(ir)...)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// validatePricing checks the pricing of a rate, if it has one, and adds every invalid field of it with add
+func validatePricing(p *Pricing, add func(field, format string, args ...interface{})) {
+	if p == nil {
+		return
+	}
+	if p.IncrementMinutes < 0 || p.IncrementMinutes > 24*60 {
+		add("pricing.increment_minutes", "increment_minutes must be between 0 and 1440")
+	}
+	if p.PerIncrement < 0 {
+		add("pricing.per_increment", "per_increment must not be negative")
+	}
+	if p.FirstHour < 0 {
+		add("pricing.first_hour", "first_hour must not be negative")
+	}
+	if p.DailyMax < 0 {
+		add("pricing.daily_max", "daily_max must not be negative")
+	}
+}
+
+// endOfDay is the end of the day in seconds since midnight, which is written as 2400 or 24:00:00
+const endOfDay = 24 * 3600
+
+// parseTimes parses a time range in the format HHMM-HHMM or HH:MM:SS-HH:MM:SS into seconds since midnight.
+// The end time may be 2400 or 24:00:00 to denote the end of the day.
+func parseTimes(times string) (start, end int, err error) {
+	timeRange := strings.Split(times, "-")
+	if len(timeRange) != 2 {
+		return 0, 0, fmt.Errorf("times must be in the format HHMM-HHMM or HH:MM:SS-HH:MM:SS: %q", times)
+	}
+	start, err = parseClock(timeRange[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err = parseClock(timeRange[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	if start >= end {
+		return 0, 0, fmt.Errorf("end time must be after start time: %q", times)
+	}
+	return start, end, nil
+}
+
+// parseClock parses a single HHMM or HH:MM:SS time into seconds since midnight.
+// 2400 and 24:00:00 are accepted as the end of the day.
+func parseClock(clock string) (int, error) {
+	var fields []string
+	switch {
+	case len(clock) == 4:
+		fields = []string{clock[:2], clock[2:]}
+	case len(clock) == 8 && clock[2] == ':' && clock[5] == ':':
+		fields = []string{clock[:2], clock[3:5], clock[6:]}
+	default:
+		return 0, fmt.Errorf("time must be in the format HHMM or HH:MM:SS: %q", clock)
+	}
+	var parts []int
+	for _, f := range fields {
+		if strings.Trim(f, "0123456789") != "" {
+			return 0, fmt.Errorf("time must be in the format HHMM or HH:MM:SS: %q", clock)
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return 0, fmt.Errorf("time must be in the format HHMM or HH:MM:SS: %q", clock)
+		}
+		parts = append(parts, n)
+	}
+	// Seconds are optional in the HHMM format
+	parts = append(parts, 0)
+	h, m, s := parts[0], parts[1], parts[2]
+	if h == 24 && m == 0 && s == 0 {
+		return endOfDay, nil
+	}
+	if h >= 24 {
+		return 0, fmt.Errorf("hours must be less than 24: %q", clock)
+	}
+	if m >= 60 {
+		return 0, fmt.Errorf("minutes must be less than 60: %q", clock)
+	}
+	if s >= 60 {
+		return 0, fmt.Errorf("seconds must be less than 60: %q", clock)
+	}
+	return h*3600 + m*60 + s, nil
+}
+
+// formatTimes formats a time range in seconds since midnight like the times of a rate. It is written as
+// HHMM-HHMM unless either time has seconds, in which case it is written as HH:MM:SS-HH:MM:SS.
+func formatTimes(start, end int) string {
+	if start%60 == 0 && end%60 == 0 {
+		return fmt.Sprintf("%02d%02d-%02d%02d", start/3600, start%3600/60, end/3600, end%3600/60)
+	}
+	return fmt.Sprintf("%02d:%02d:%02d-%02d:%02d:%02d", start/3600, start%3600/60, start%60, end/3600, end%3600/60, end%60)
+}
@@ -0,0 +1,197 @@
+package rates
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		rates  []RateDetail
+		errors []FieldError
+	}{
+		{
+			name: "Valid rates",
+			rates: []RateDetail{
+				{Days: "mon,tues,thurs", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+				{Days: "sun", Times: "0000-2400", TZ: "UTC", Price: 0},
+			},
+			errors: nil,
+		},
+		{
+			name: "Times without a dash",
+			rates: []RateDetail{
+				{Days: "mon", Times: "0900", TZ: "America/Chicago", Price: 1500},
+			},
+			errors: []FieldError{
+				{Index: 0, Field: "times", Message: `times must be in the format HHMM-HHMM or HH:MM:SS-HH:MM:SS: "0900"`},
+			},
+		},
+		{
+			name: "Times out of range",
+			rates: []RateDetail{
+				{Days: "mon", Times: "2500-2600", TZ: "America/Chicago", Price: 1500},
+				{Days: "mon", Times: "0960-1000", TZ: "America/Chicago", Price: 1500},
+				{Days: "mon", Times: "9am-5pm", TZ: "America/Chicago", Price: 1500},
+			},
+			errors: []FieldError{
+				{Index: 0, Field: "times", Message: `hours must be less than 24: "2500"`},
+				{Index: 1, Field: "times", Message: `minutes must be less than 60: "0960"`},
+				{Index: 2, Field: "times", Message: `time must be in the format HHMM or HH:MM:SS: "9am"`},
+			},
+		},
+		{
+			name: "Times with seconds",
+			rates: []RateDetail{
+				{Days: "mon", Times: "09:00:30-17:00:00", TZ: "America/Chicago", Price: 1500},
+				{Days: "mon", Times: "17:00:00-24:00:00", TZ: "America/Chicago", Price: 1500},
+				{Days: "mon", Times: "09:00:60-1700", TZ: "America/Chicago", Price: 1500},
+				{Days: "mon", Times: "9:00:00-17:00:00", TZ: "America/Chicago", Price: 1500},
+				{Days: "mon", Times: "24:00:01-24:00:02", TZ: "America/Chicago", Price: 1500},
+			},
+			errors: []FieldError{
+				{Index: 2, Field: "times", Message: `seconds must be less than 60: "09:00:60"`},
+				{Index: 3, Field: "times", Message: `time must be in the format HHMM or HH:MM:SS: "9:00:00"`},
+				{Index: 4, Field: "times", Message: `hours must be less than 24: "24:00:01"`},
+			},
+		},
+		{
+			name: "Unknown boundary",
+			rates: []RateDetail{
+				{Days: "mon", Times: "0900-1200", TZ: "America/Chicago", Price: 1500, Boundary: BoundaryClosed},
+				{Days: "mon", Times: "1200-2100", TZ: "America/Chicago", Price: 1500, Boundary: "inclusive"},
+			},
+			errors: []FieldError{
+				{Index: 1, Field: "boundary", Message: `unknown boundary: "inclusive"`},
+			},
+		},
+		{
+			name: "Duplicate ids",
+			rates: []RateDetail{
+				{ID: "weekdays", Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+				{ID: "weekdays", Days: "tues", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+				{Days: "wed", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+				{Days: "thurs", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+			},
+			errors: []FieldError{
+				{Index: 1, Field: "id", Message: `duplicate id: "weekdays"`},
+			},
+		},
+		{
+			name: "Start time after end time",
+			rates: []RateDetail{
+				{Days: "mon", Times: "2100-0900", TZ: "America/Chicago", Price: 1500},
+			},
+			errors: []FieldError{
+				{Index: 0, Field: "times", Message: `end time must be after start time: "2100-0900"`},
+			},
+		},
+		{
+			name: "Invalid pricing",
+			rates: []RateDetail{
+				{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Pricing: &Pricing{PerIncrement: 200}},
+				{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Pricing: &Pricing{
+					IncrementMinutes: -15,
+					PerIncrement:     -1,
+					FirstHour:        -1,
+					DailyMax:         -1,
+				}},
+			},
+			errors: []FieldError{
+				{Index: 1, Field: "pricing.increment_minutes", Message: "increment_minutes must be between 0 and 1440"},
+				{Index: 1, Field: "pricing.per_increment", Message: "per_increment must not be negative"},
+				{Index: 1, Field: "pricing.first_hour", Message: "first_hour must not be negative"},
+				{Index: 1, Field: "pricing.daily_max", Message: "daily_max must not be negative"},
+			},
+		},
+		{
+			name: "Every invalid field is listed by the index of its rate",
+			rates: []RateDetail{
+				{Days: "mon", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+				{Days: "mon,tue,funday", Times: "0900-2100", TZ: "Mars/Olympus_Mons", Price: -1},
+				{Days: "wed", Times: "0900-2100", TZ: "", Price: 1500},
+			},
+			errors: []FieldError{
+				{Index: 1, Field: "days", Message: `unknown day: "tue"`},
+				{Index: 1, Field: "days", Message: `unknown day: "funday"`},
+				{Index: 1, Field: "tz", Message: `unknown timezone: "Mars/Olympus_Mons"`},
+				{Index: 1, Field: "price", Message: "price must not be negative"},
+				{Index: 2, Field: "tz", Message: "tz is required"},
+			},
+		},
+	}
+	for _, tt := range testCases {
+		err := Validate(IncomingRates{Rates: tt.rates})
+		if tt.errors == nil {
+			assert.Nil(t, err, tt.name)
+			continue
+		}
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr), tt.name)
+		assert.Equal(t, tt.errors, validationErr.Errors, tt.name)
+	}
+}
+
+func TestValidateEffectiveUntil(t *testing.T) {
+	effectiveFrom := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	before := effectiveFrom.Add(-time.Hour)
+	after := effectiveFrom.Add(time.Hour)
+	ir := IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon", Times: "0900-2100", TZ: "UTC", Price: 1500, EffectiveUntil: &after},
+			{Days: "tues", Times: "0900-2100", TZ: "UTC", Price: 1500, EffectiveUntil: &before},
+		},
+		EffectiveFrom: &effectiveFrom,
+	}
+	err := Validate(ir)
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, []FieldError{
+		{Index: 1, Field: "effective_until", Message: "effective_until must be after effective_from"},
+	}, validationErr.Errors)
+
+	// effective_until can be anything when the rates take effect immediately
+	ir.EffectiveFrom = nil
+	assert.Nil(t, Validate(ir))
+}
+
+func TestPutRejectsMalformedRates(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+
+	// A time range without a dash used to panic
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon", Times: "0900", TZ: "America/Chicago", Price: 1500},
+		},
+	})
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	// assert that the rejected rates were not put in place
+	assert.Len(t, a.List().Rates, 5)
+}
+
+func TestParseAndFormatTimes(t *testing.T) {
+	testCases := []struct {
+		times      string
+		start, end int
+		formatted  string
+	}{
+		{"0900-2100", 32400, 75600, "0900-2100"},
+		{"0000-2400", 0, 86400, "0000-2400"},
+		{"09:00:00-21:00:00", 32400, 75600, "0900-2100"},
+		{"09:00:30-2100", 32430, 75600, "09:00:30-21:00:00"},
+		{"23:59:59-24:00:00", 86399, 86400, "23:59:59-24:00:00"},
+	}
+	for _, tt := range testCases {
+		start, end, err := parseTimes(tt.times)
+		assert.Nil(t, err, tt.times)
+		assert.Equal(t, tt.start, start, tt.times)
+		assert.Equal(t, tt.end, end, tt.times)
+		assert.Equal(t, tt.formatted, formatTimes(start, end), tt.times)
+	}
+}
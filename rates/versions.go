@@ -0,0 +1,151 @@
+package rates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrVersionNotFound is returned when there is no version of the rates with the requested id
+var ErrVersionNotFound = errors.New("version not found")
+
+// Version is an immutable record of a set of rates that was accepted by Put
+type Version struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	IncomingRates
+}
+
+// Versions returns every accepted set of rates, oldest first
+func (a *API) Versions() []Version {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	versions := make([]Version, len(a.versions))
+	for i, v := range a.versions {
+		versions[i] = v.copy()
+	}
+	return versions
+}
+
+// Version returns the accepted set of rates with the given id
+func (a *API) Version(id int) (Version, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	v, ok := a.version(id)
+	if !ok {
+		return Version{}, ErrVersionNotFound
+	}
+	return v.copy(), nil
+}
+
+// Rollback puts the rates of the version with the given id back in place.
+// The rates take effect immediately, even when the version was scheduled to take effect later.
+// The rollback is recorded as a new version, so the history is never rewritten.
+// caller is the authenticated client that rolled back, which is recorded in the audit log.
+func (a *API) Rollback(id int, author string, caller Caller) (Version, error) {
+	a.mu.Lock()
+	v, ok := a.version(id)
+	a.mu.Unlock()
+	if !ok {
+		return Version{}, ErrVersionNotFound
+	}
+
+	ir := v.copy().IncomingRates
+	ir.Author = author
+	ir.Comment = fmt.Sprintf("rollback to version %d", id)
+	ir.EffectiveFrom = nil
+	ir.Caller = caller
+	return a.put(ir)
+}
+
+// version looks up the version with the given id. The caller must hold a.mu.
+func (a *API) version(id int) (Version, bool) {
+	// Versions are numbered from 1 in the order they were accepted
+	if id < 1 || id > len(a.versions) {
+		return Version{}, false
+	}
+	return a.versions[id-1], true
+}
+
+// copy returns a copy of the version that doesn't share its rates or overrides with the original
+func (v Version) copy() Version {
+	v.IncomingRates = copyRates(v.IncomingRates)
+	return v
+}
+
+// FileVersionLog is a VersionLog that appends every version to a file as a line of JSON
+type FileVersionLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileVersionLog returns a FileVersionLog that appends versions to the file at path
+// The file is created when the first version is appended
+func NewFileVersionLog(path string) *FileVersionLog {
+	return &FileVersionLog{path: path}
+}
+
+// Append writes the version to the end of the file. The file is never truncated or rewritten.
+func (f *FileVersionLog) Append(v Version) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return appendLine(f.path, v)
+}
+
+// Load reads every version from the file, oldest first. It returns no versions if the file doesn't exist.
+func (f *FileVersionLog) Load() ([]Version, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	versions := []Version{}
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return versions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+	for {
+		var v Version
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return versions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+}
+
+// restoreVersions replaces the versions of the rates that were loaded on startup with the versions of log,
+// so that the versions and their ids carry over a restart. An empty log is appended the loaded versions instead.
+func (a *API) restoreVersions(log VersionLog) error {
+	versions, err := log.Load()
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		for _, v := range a.versions {
+			if err := log.Append(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	a.versions = versions
+	// The ids of rates that were removed since are never given out again
+	for _, v := range versions {
+		a.reserveIDs(v.Rates)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package rates
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersions(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+
+	// assert that the seed rates are recorded as the first version
+	versions := a.Versions()
+	assert.Len(t, versions, 1)
+	assert.Equal(t, 1, versions[0].ID)
+	assert.Len(t, versions[0].Rates, 5)
+	assert.False(t, versions[0].CreatedAt.IsZero())
+
+	ir := IncomingRates{
+		Rates: []RateDetail{
+			{ID: "weekdays", Days: "mon,tues,thurs", Times: "0900-2100", TZ: "America/Chicago", Price: 1500},
+		},
+		Overrides: []Override{{Name: "Christmas", Annual: "12-25", TZ: "America/Chicago", Rates: []OverrideRate{}}},
+		Author:    "pricing",
+		Comment:   "summer rates",
+	}
+	err = a.Put(ir)
+	assert.Nil(t, err)
+
+	v, err := a.Version(2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, v.ID)
+	assert.Equal(t, ir, v.IncomingRates)
+
+	// assert that rejected rates are not recorded as a version
+	err = a.Put(IncomingRates{Rates: []RateDetail{{Days: "mon", Times: "0900", TZ: "UTC"}}})
+	assert.NotNil(t, err)
+	assert.Len(t, a.Versions(), 2)
+
+	// assert that versions can't be modified through the returned copies
+	v.Rates[0].Price = 1
+	v.Overrides[0].Name = "Boxing Day"
+	v, err = a.Version(2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1500, v.Rates[0].Price)
+	assert.Equal(t, "Christmas", v.Overrides[0].Name)
+	a.Versions()[1].Overrides[0].Name = "Boxing Day"
+	assert.Equal(t, "Christmas", a.List().Overrides[0].Name)
+
+	for _, id := range []int{0, 3, -1} {
+		_, err = a.Version(id)
+		assert.Equal(t, ErrVersionNotFound, err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	a, err := NewAPI("seed_rates.json")
+	assert.Nil(t, err)
+	seed := a.List()
+	// 10am on a Monday in Chicago, which is priced at 1500 by the seed rates
+	seedMondayMorning := time.Date(2020, 4, 6, 15, 0, 0, 0, time.UTC)
+
+	err = a.Put(IncomingRates{
+		Rates: []RateDetail{
+			{Days: "mon,tues,thurs", Times: "0900-2100", TZ: "America/Chicago", Price: 99999},
+		},
+	})
+	assert.Nil(t, err)
+
+	v, err := a.Rollback(1, "ops", Caller{})
+	assert.Nil(t, err)
+	// assert that the rollback is recorded as a new version with the rates of the old version
+	assert.Equal(t, 3, v.ID)
+	assert.Equal(t, seed.Rates, v.Rates)
+	assert.Equal(t, "ops", v.Author)
+	assert.Equal(t, "rollback to version 1", v.Comment)
+	assert.Len(t, a.Versions(), 3)
+
+	// assert that the rates of the old version are back in place
+	assert.Equal(t, seed.Rates, a.List().Rates)
+	rate, err := a.Get(ParkingTimesRequest{
+		StartTime: seedMondayMorning,
+		EndTime:   seedMondayMorning.Add(time.Hour),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1500, rate)
+
+	_, err = a.Rollback(10, "ops", Caller{})
+	assert.Equal(t, ErrVersionNotFound, err)
+	assert.Len(t, a.Versions(), 3)
+
+	// assert that rolling back to a version that is still scheduled puts its rates in place immediately
+	effectiveFrom := time.Now().Add(24 * time.Hour)
+	scheduled := IncomingRates{
+		Rates:         []RateDetail{{Days: "mon", Times: "0000-2400", TZ: "UTC", Price: 700}},
+		EffectiveFrom: &effectiveFrom,
+	}
+	assert.Nil(t, a.Put(scheduled))
+	assert.Len(t, a.Pending(), 1)
+	v, err = a.Rollback(4, "ops", Caller{})
+	assert.Nil(t, err)
+	assert.Nil(t, v.EffectiveFrom)
+	assert.Equal(t, []RateDetail{{ID: "7", Days: "mon", Times: "0000-2400", TZ: "UTC", Price: 700}}, a.List().Rates)
+	assert.Len(t, a.Pending(), 1)
+}
+
+func TestFileVersionLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	l := NewFileVersionLog(filepath.Join(dir, "versions.jsonl"))
+	versions, err := l.Load()
+	assert.Nil(t, err)
+	assert.Empty(t, versions)
+
+	first := Version{ID: 1, CreatedAt: time.Date(2020, 4, 6, 9, 0, 0, 0, time.UTC), IncomingRates: storedRates}
+	second := Version{ID: 2, CreatedAt: first.CreatedAt.Add(time.Hour), IncomingRates: storedRates}
+	assert.Nil(t, l.Append(first))
+	assert.Nil(t, l.Append(second))
+
+	// assert that a new log reads the versions that are already in the file
+	l = NewFileVersionLog(filepath.Join(dir, "versions.jsonl"))
+	versions, err = l.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, []Version{first, second}, versions)
+}
+
+func TestVersionsAfterRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	opts := Options{
+		Store:    NewFileStore(filepath.Join(dir, "rates.json")),
+		Audit:    NewFileAuditLog(filepath.Join(dir, "audit.jsonl")),
+		Versions: NewFileVersionLog(filepath.Join(dir, "versions.jsonl")),
+	}
+	a, err := NewAPIWithOptions("seed_rates.json", opts)
+	assert.Nil(t, err)
+	seed := a.List()
+	err = a.Put(IncomingRates{Rates: []RateDetail{{Days: "mon", Times: "0000-2400", TZ: "UTC", Price: 700}}})
+	assert.Nil(t, err)
+	assert.Nil(t, a.Put(storedRates))
+	versions := a.Versions()
+	assert.Len(t, versions, 3)
+
+	// assert that the versions and their ids carry over a restart
+	a, err = NewAPIWithOptions("seed_rates.json", opts)
+	assert.Nil(t, err)
+	assert.Equal(t, versions, a.Versions())
+	assert.Equal(t, storedRates, a.List())
+
+	// assert that the versions from before the restart can be rolled back to, and are numbered after
+	v, err := a.Rollback(1, "ops", Caller{})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, v.ID)
+	assert.Equal(t, seed.Rates, a.List().Rates)
+	assert.Len(t, a.Versions(), 4)
+
+	// assert that the ids of rates that are no longer active aren't given out again
+	ir, err := a.Patch(RatePatch{Add: []RateDetail{{Days: "wed", Times: "1900-2100", TZ: "America/Chicago", Price: 900}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "7", ir.Rates[len(ir.Rates)-1].ID)
+
+	// assert that every version is audited once
+	records, err := a.Audit(time.Time{})
+	assert.Nil(t, err)
+	var ids []int
+	for _, r := range records {
+		ids = append(ids, r.Version)
+	}
+	assert.Equal(t, []int{2, 3, 4, 5}, ids)
+}